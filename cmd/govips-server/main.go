@@ -0,0 +1,110 @@
+// Command govips-server serves images transformed according to the request URL, e.g.
+//
+//	GET /resize/fit/300/200/format:webp/photos/cat.jpg
+//
+// See the imageserver package for the URL options.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/davidbyttow/govips/v2/imageserver"
+	"github.com/davidbyttow/govips/v2/vips"
+)
+
+// defaultLoaders are the source formats decoded unless -loaders is given
+const defaultLoaders = "jpeg,png,webp,gif,heif,avif,tiff"
+
+// parseLoaders parses the comma separated format names of the -loaders flag
+func parseLoaders(names string) ([]vips.ImageType, error) {
+	var types []vips.ImageType
+	for _, name := range strings.Split(names, ",") {
+		imageType := vips.ParseImageType(name)
+		if imageType == vips.ImageTypeUnknown {
+			return nil, fmt.Errorf("unknown loader %q", strings.TrimSpace(name))
+		}
+		types = append(types, imageType)
+	}
+	return types, nil
+}
+
+func main() {
+	addr := flag.String("addr", ":8080", "Address to listen on")
+	root := flag.String("root", "", "Directory to serve source images from")
+	allowRemote := flag.Bool("allow-remote", false, "Allow http and https URLs as sources; requires -key")
+	allowedHosts := flag.String("allowed-hosts", "", "Comma separated hosts remote sources may come from, e.g. images.example.com,*.cdn.example.com; any public host if empty")
+	key := flag.String("key", os.Getenv("GOVIPS_SERVER_KEY"), "Key for signed URLs; unsigned URLs are accepted if empty (default $GOVIPS_SERVER_KEY)")
+	maxPixels := flag.Int("max-pixels", imageserver.DefaultMaxSourcePixels, "Largest source image in pixels")
+	maxWidth := flag.Int("max-width", imageserver.DefaultMaxDimension, "Largest output width")
+	maxHeight := flag.Int("max-height", imageserver.DefaultMaxDimension, "Largest output height")
+	loaders := flag.String("loaders", defaultLoaders, "Comma separated source formats that may be decoded")
+	blockUntrusted := flag.Bool("block-untrusted", true, "Block the libvips operations marked as untrusted; requires libvips 8.13+")
+	cacheMaxAge := flag.Duration("cache-max-age", imageserver.DefaultCacheMaxAge, "Max age sent in the Cache-Control header")
+	flag.Parse()
+
+	var fetchers imageserver.Fetchers
+	if *root != "" {
+		fetchers = append(fetchers, imageserver.Dir(*root))
+	}
+	if *allowRemote {
+		// Unsigned remote sources would let anyone use the server as a proxy
+		if *key == "" {
+			fmt.Fprintln(os.Stderr, "Error: -allow-remote requires -key")
+			os.Exit(1)
+		}
+		fetcher := &imageserver.HTTPFetcher{}
+		if *allowedHosts != "" {
+			fetcher.AllowedHosts = strings.Split(*allowedHosts, ",")
+		}
+		fetchers = append(fetchers, fetcher)
+	}
+	if len(fetchers) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: -root or -allow-remote is required")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	allowedLoaders, err := parseLoaders(*loaders)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Sources are untrusted, so only the common, well fuzzed loaders are enabled
+	if err := vips.Startup(&vips.Config{
+		CollectStats:   true,
+		AllowedLoaders: allowedLoaders,
+		BlockUntrusted: *blockUntrusted,
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer vips.Shutdown()
+
+	server := imageserver.New(imageserver.Config{
+		Fetcher:         fetchers,
+		Key:             []byte(*key),
+		MaxSourcePixels: *maxPixels,
+		MaxWidth:        *maxWidth,
+		MaxHeight:       *maxHeight,
+		CacheMaxAge:     *cacheMaxAge,
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", server.MetricsHandler())
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok\n"))
+	})
+	mux.Handle("/", server)
+
+	log.Printf("govips-server listening on %s", *addr)
+	if err := http.ListenAndServe(*addr, mux); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+func runBatch(args []string) error {
+	fs := newFlagSet("batch", "[flags] -output template pattern...")
+	template := fs.String("output", "", "Output path template, where {dir} is the directory of the input,\n{name} its file name without extension and {ext} its extension, e.g. out/{name}.webp")
+	workers := fs.Int("workers", runtime.NumCPU(), "Number of images processed in parallel")
+	verbose := fs.Bool("v", false, "Print each file as it is written")
+	var imports importFlags
+	var exports exportFlags
+	var thumbnails thumbnailFlags
+	imports.register(fs)
+	exports.register(fs)
+	thumbnails.register(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 || *template == "" {
+		fs.Usage()
+		return errUsage
+	}
+	if *workers < 1 {
+		return fmt.Errorf("-workers must be at least 1")
+	}
+
+	// Images are thumbnailed when a size is given and converted otherwise
+	var t *thumbnail
+	if thumbnails.enabled() {
+		var err error
+		if t, err = thumbnails.thumbnail(); err != nil {
+			return err
+		}
+	}
+
+	jobs, err := batchJobs(fs.Args(), *template)
+	if err != nil {
+		return err
+	}
+
+	var lock sync.Mutex
+	failed := 0
+	queue := make(chan [2]string)
+	var wg sync.WaitGroup
+	for i := 0; i < *workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				input, output := job[0], job[1]
+				err := os.MkdirAll(filepath.Dir(output), 0o755)
+				if err == nil {
+					err = process(input, output, &imports, &exports, t)
+				}
+
+				lock.Lock()
+				if err != nil {
+					failed++
+					fmt.Fprintf(os.Stderr, "%s: %v\n", input, err)
+				} else if *verbose {
+					fmt.Printf("%s -> %s\n", input, output)
+				}
+				lock.Unlock()
+			}
+		}()
+	}
+
+	for _, job := range jobs {
+		queue <- job
+	}
+	close(queue)
+	wg.Wait()
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files failed", failed, len(jobs))
+	}
+	return nil
+}
+
+// batchJobs expands the glob patterns and returns the input and output path of each file
+func batchJobs(patterns []string, template string) ([][2]string, error) {
+	var jobs [][2]string
+	outputs := make(map[string]string)
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", pattern, err)
+		}
+		for _, input := range matches {
+			if info, err := os.Stat(input); err != nil || info.IsDir() {
+				continue
+			}
+
+			output := expandTemplate(template, input)
+			if other, ok := outputs[output]; ok {
+				if other == input {
+					continue
+				}
+				return nil, fmt.Errorf("%s and %s would both be written to %s", other, input, output)
+			}
+			if filepath.Clean(output) == filepath.Clean(input) {
+				return nil, fmt.Errorf("%s would be overwritten by its output", input)
+			}
+			outputs[output] = input
+			jobs = append(jobs, [2]string{input, output})
+		}
+	}
+
+	if len(jobs) == 0 {
+		return nil, fmt.Errorf("no files match %s", strings.Join(patterns, " "))
+	}
+	return jobs, nil
+}
+
+// expandTemplate returns the output path of input for an output path template
+func expandTemplate(template, input string) string {
+	base := filepath.Base(input)
+	ext := filepath.Ext(base)
+	return strings.NewReplacer(
+		"{dir}", filepath.Dir(input),
+		"{name}", strings.TrimSuffix(base, ext),
+		"{ext}", strings.TrimPrefix(ext, "."),
+	).Replace(template)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandTemplate(t *testing.T) {
+	input := filepath.Join("photos", "2024", "cat.large.JPG")
+	assert.Equal(t, filepath.Join("out", "cat.large.webp"), expandTemplate(filepath.Join("out", "{name}.webp"), input))
+	assert.Equal(t, filepath.Join("photos", "2024", "thumbs", "cat.large-small.JPG"),
+		expandTemplate(filepath.Join("{dir}", "thumbs", "{name}-small.{ext}"), input))
+	assert.Equal(t, "README", expandTemplate("{name}", "README"))
+	assert.Equal(t, "", expandTemplate("{ext}", "README"))
+}
+
+func writeFiles(t *testing.T, dir string, names ...string) {
+	for _, name := range names {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0o644))
+	}
+}
+
+func TestBatchJobs(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "a.jpg", "b.png", "c.txt")
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "d.jpg"), 0o755))
+
+	// Files matching several patterns are processed once and directories are skipped
+	jobs, err := batchJobs([]string{filepath.Join(dir, "*.jpg"), filepath.Join(dir, "*.png"), filepath.Join(dir, "a.*")},
+		filepath.Join(dir, "out", "{name}.webp"))
+	require.NoError(t, err)
+	assert.Equal(t, [][2]string{
+		{filepath.Join(dir, "a.jpg"), filepath.Join(dir, "out", "a.webp")},
+		{filepath.Join(dir, "b.png"), filepath.Join(dir, "out", "b.webp")},
+	}, jobs)
+}
+
+func TestBatchJobs_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "a.jpg", "a.png")
+
+	_, err := batchJobs([]string{filepath.Join(dir, "*.gif")}, "{name}.webp")
+	assert.Error(t, err)
+
+	_, err = batchJobs([]string{filepath.Join(dir, "[")}, "{name}.webp")
+	assert.Error(t, err)
+
+	// Both inputs would be written to the same file
+	_, err = batchJobs([]string{filepath.Join(dir, "a.*")}, filepath.Join(dir, "{name}.webp"))
+	assert.Error(t, err)
+
+	// The input would be overwritten
+	_, err = batchJobs([]string{filepath.Join(dir, "a.jpg")}, filepath.Join("{dir}", "{name}.{ext}"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,41 @@
+package main
+
+func runConvert(args []string) error {
+	fs := newFlagSet("convert", "[flags] input output")
+	var imports importFlags
+	var exports exportFlags
+	imports.register(fs)
+	exports.register(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return errUsage
+	}
+	return process(fs.Arg(0), fs.Arg(1), &imports, &exports, nil)
+}
+
+func runThumbnail(args []string) error {
+	fs := newFlagSet("thumbnail", "[flags] input output")
+	var imports importFlags
+	var exports exportFlags
+	var thumbnails thumbnailFlags
+	imports.register(fs)
+	exports.register(fs)
+	thumbnails.register(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return errUsage
+	}
+	t, err := thumbnails.thumbnail()
+	if err != nil {
+		return err
+	}
+	return process(fs.Arg(0), fs.Arg(1), &imports, &exports, t)
+}
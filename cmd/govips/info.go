@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/davidbyttow/govips/v2/vips"
+)
+
+// imageInfo is the JSON output of the info command
+type imageInfo struct {
+	File        string            `json:"file"`
+	Format      string            `json:"format"`
+	Loader      string            `json:"loader"`
+	Width       int               `json:"width"`
+	Height      int               `json:"height"`
+	Pages       int               `json:"pages"`
+	PageHeight  int               `json:"pageHeight"`
+	Bands       int               `json:"bands"`
+	BandFormat  string            `json:"bandFormat"`
+	Colorspace  string            `json:"colorspace"`
+	Orientation int               `json:"orientation"`
+	HasAlpha    bool              `json:"hasAlpha"`
+	HasICC      bool              `json:"hasICCProfile"`
+	HasExif     bool              `json:"hasExif"`
+	HasXMP      bool              `json:"hasXMP"`
+	Exif        map[string]string `json:"exif,omitempty"`
+	Fields      map[string]string `json:"fields,omitempty"`
+}
+
+func runInfo(args []string) error {
+	fs := newFlagSet("info", "[flags] file...")
+	asJSON := fs.Bool("json", false, "Print the information as JSON")
+	exif := fs.Bool("exif", false, "Print the EXIF tags")
+	fields := fs.Bool("fields", false, "Print all header fields with their values")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	var infos []*imageInfo
+	for _, file := range fs.Args() {
+		info, err := readInfo(file, *exif, *fields)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		infos = append(infos, info)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if len(infos) == 1 {
+			return enc.Encode(infos[0])
+		}
+		return enc.Encode(infos)
+	}
+
+	for i, info := range infos {
+		if i > 0 {
+			fmt.Println()
+		}
+		printInfo(info)
+	}
+	return nil
+}
+
+func readInfo(file string, exif, fields bool) (*imageInfo, error) {
+	// ProbeFile fills in all of ImageMetadata without decoding any pixels
+	metadata, err := vips.ProbeFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := vips.LoadImageFromFile(file, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer img.Close()
+
+	info := &imageInfo{
+		File:        file,
+		Format:      vips.ImageTypes[metadata.Format],
+		Loader:      metadata.Loader,
+		Width:       metadata.Width,
+		Height:      metadata.Height,
+		Pages:       metadata.Pages,
+		PageHeight:  metadata.PageHeight,
+		Bands:       metadata.Bands,
+		BandFormat:  img.GetAsString("format"),
+		Colorspace:  img.GetAsString("interpretation"),
+		Orientation: metadata.Orientation,
+		HasAlpha:    img.HasAlpha(),
+		HasICC:      metadata.HasICCProfile,
+		HasExif:     metadata.HasExif,
+		HasXMP:      metadata.HasXMP,
+	}
+	if exif {
+		info.Exif = img.GetExif()
+	}
+	if fields {
+		info.Fields = make(map[string]string)
+		for _, name := range img.ImageFields() {
+			info.Fields[name] = img.GetAsString(name)
+		}
+	}
+	return info, nil
+}
+
+func printInfo(info *imageInfo) {
+	fmt.Printf("%s\n", info.File)
+	fmt.Printf("  format:      %s (%s)\n", info.Format, info.Loader)
+	fmt.Printf("  size:        %dx%d\n", info.Width, info.Height)
+	if info.Pages > 1 {
+		fmt.Printf("  pages:       %d of height %d\n", info.Pages, info.PageHeight)
+	}
+	fmt.Printf("  bands:       %d %s\n", info.Bands, info.BandFormat)
+	fmt.Printf("  colorspace:  %s\n", info.Colorspace)
+	fmt.Printf("  orientation: %d\n", info.Orientation)
+	fmt.Printf("  alpha:       %s\n", yesNo(info.HasAlpha))
+	fmt.Printf("  icc profile: %s\n", yesNo(info.HasICC))
+	fmt.Printf("  exif:        %s\n", yesNo(info.HasExif))
+	fmt.Printf("  xmp:         %s\n", yesNo(info.HasXMP))
+	printMap("exif tags", info.Exif)
+	printMap("fields", info.Fields)
+}
+
+func printMap(title string, m map[string]string) {
+	if len(m) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fmt.Printf("  %s:\n", title)
+	for _, key := range keys {
+		fmt.Printf("    %s: %s\n", key, m[key])
+	}
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
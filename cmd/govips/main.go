@@ -0,0 +1,103 @@
+// Command govips inspects, converts and thumbnails images through the same govips calls
+// used by Go services, which makes it handy to reproduce their behaviour from a shell.
+//
+//	govips info [flags] file...
+//	govips convert [flags] input output
+//	govips thumbnail [flags] input output
+//	govips batch [flags] -output template pattern...
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/davidbyttow/govips/v2/vips"
+)
+
+// errUsage is returned by commands for invalid arguments, once their usage has been printed
+var errUsage = errors.New("invalid usage")
+
+type command struct {
+	name        string
+	description string
+	run         func(args []string) error
+}
+
+var commands = []command{
+	{"info", "Print the metadata, EXIF and header fields of images", runInfo},
+	{"convert", "Convert an image to the format of the output file", runConvert},
+	{"thumbnail", "Write a thumbnail of an image", runThumbnail},
+	{"batch", "Convert or thumbnail many images in parallel", runBatch},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: govips <command> [flags] [arguments]")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", c.name, c.description)
+	}
+	fmt.Fprintln(os.Stderr, "\nRun govips <command> -h for the flags of a command.")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	name := os.Args[1]
+	if name == "-h" || name == "-help" || name == "--help" || name == "help" {
+		usage()
+		return
+	}
+
+	for _, c := range commands {
+		if c.name != name {
+			continue
+		}
+
+		vips.LoggingSettings(nil, vips.LogLevelWarning)
+		if err := vips.Startup(nil); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		err := c.run(os.Args[2:])
+		vips.Shutdown()
+		switch {
+		case err == nil, errors.Is(err, flag.ErrHelp):
+		case errors.Is(err, errUsage):
+			os.Exit(2)
+		default:
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "Error: unknown command %q\n\n", name)
+	usage()
+	os.Exit(2)
+}
+
+// newFlagSet creates the flag set of a command, printing usage as its synopsis
+func newFlagSet(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: govips %s %s\n\nFlags:\n", name, usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags parses the flags of a command. Invalid flags, which fs reports itself, give errUsage.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	return nil
+}
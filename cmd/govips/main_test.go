@@ -0,0 +1,24 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFlags(t *testing.T) {
+	fs := newFlagSet("test", "[flags]")
+	fs.SetOutput(io.Discard)
+	fs.Bool("v", false, "Verbose")
+
+	assert.NoError(t, parseFlags(fs, []string{"-v", "input"}))
+	assert.True(t, errors.Is(parseFlags(fs, []string{"-unknown"}), errUsage))
+	assert.True(t, errors.Is(parseFlags(fs, []string{"-h"}), flag.ErrHelp))
+}
+
+func TestRunConvert__Usage(t *testing.T) {
+	assert.True(t, errors.Is(runConvert([]string{"input.jpg"}), errUsage))
+}
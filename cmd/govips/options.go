@@ -0,0 +1,284 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+
+	"github.com/davidbyttow/govips/v2/vips"
+)
+
+// importFlags are the ImportParams flags shared by convert, thumbnail and batch
+type importFlags struct {
+	fs         *flag.FlagSet
+	autoRotate bool
+	fail       bool
+	page       int
+	pages      int
+	density    int
+	sequential bool
+	maxPixels  int
+}
+
+func (f *importFlags) register(fs *flag.FlagSet) {
+	f.fs = fs
+	fs.BoolVar(&f.autoRotate, "autorotate", false, "Rotate the image upright according to its EXIF orientation")
+	fs.BoolVar(&f.fail, "fail", true, "Fail on the first decoding error instead of loading truncated images")
+	fs.IntVar(&f.page, "page", 0, "First page to load from multi-page images")
+	fs.IntVar(&f.pages, "pages", 0, "Number of pages to load, -1 for all")
+	fs.IntVar(&f.density, "density", 0, "Density in DPI for vector formats such as PDF and SVG")
+	fs.BoolVar(&f.sequential, "sequential", false, "Load with sequential access to bound memory use")
+	fs.IntVar(&f.maxPixels, "max-pixels", 0, "Refuse images with more pixels than this")
+}
+
+// params returns the ImportParams of the flags that were given
+func (f *importFlags) params() *vips.ImportParams {
+	params := vips.NewImportParams()
+	f.fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "autorotate":
+			params.AutoRotate.Set(f.autoRotate)
+		case "fail":
+			params.FailOnError.Set(f.fail)
+		case "page":
+			params.Page.Set(f.page)
+		case "pages":
+			params.NumPages.Set(f.pages)
+		case "density":
+			params.Density.Set(f.density)
+		case "sequential":
+			if f.sequential {
+				params.Access.Set(vips.AccessSequential)
+			}
+		case "max-pixels":
+			params.MaxPixels.Set(f.maxPixels)
+		}
+	})
+	return params
+}
+
+// exportFlags are the export params flags shared by convert, thumbnail and batch. Flags
+// which are not given keep the defaults of the New*ExportParams functions.
+type exportFlags struct {
+	fs          *flag.FlagSet
+	format      string
+	quality     int
+	strip       bool
+	lossless    bool
+	effort      int
+	compression int
+	interlace   bool
+}
+
+func (f *exportFlags) register(fs *flag.FlagSet) {
+	f.fs = fs
+	fs.StringVar(&f.format, "format", "", "Output format, instead of the one of the output file extension")
+	fs.IntVar(&f.quality, "quality", 0, "Quality of lossy formats from 1 to 100")
+	fs.BoolVar(&f.strip, "strip", false, "Strip metadata")
+	fs.BoolVar(&f.lossless, "lossless", false, "Use lossless compression (WebP, HEIF, AVIF, JPEG 2000, JPEG XL)")
+	fs.IntVar(&f.effort, "effort", 0, "CPU effort of the encoder (WebP, HEIF, AVIF, GIF, JPEG XL)")
+	fs.IntVar(&f.compression, "compression", 0, "PNG compression level from 0 to 9")
+	fs.BoolVar(&f.interlace, "interlace", false, "Write interlaced or progressive images (JPEG, PNG)")
+}
+
+// params returns the export params for output, whose type selects the saver used by
+// ImageRef.SaveToFile
+func (f *exportFlags) params(output string) (interface{}, error) {
+	format := vips.ParseImageType(filepath.Ext(output))
+	if f.format != "" {
+		format = vips.ParseImageType(f.format)
+	}
+
+	set := make(map[string]bool)
+	f.fs.Visit(func(fl *flag.Flag) { set[fl.Name] = true })
+
+	switch format {
+	case vips.ImageTypeJPEG:
+		params := vips.NewJpegExportParams()
+		params.StripMetadata = f.strip
+		if set["quality"] {
+			params.Quality = f.quality
+		}
+		if set["interlace"] {
+			params.Interlace = f.interlace
+		}
+		return params, nil
+	case vips.ImageTypePNG:
+		params := vips.NewPngExportParams()
+		params.StripMetadata = f.strip
+		if set["compression"] {
+			params.Compression = f.compression
+		}
+		if set["interlace"] {
+			params.Interlace = f.interlace
+		}
+		return params, nil
+	case vips.ImageTypeWEBP:
+		params := vips.NewWebpExportParams()
+		params.StripMetadata = f.strip
+		params.Lossless = f.lossless
+		if set["quality"] {
+			params.Quality = f.quality
+		}
+		if set["effort"] {
+			params.ReductionEffort = f.effort
+		}
+		return params, nil
+	case vips.ImageTypeHEIF:
+		params := vips.NewHeifExportParams()
+		params.Lossless = f.lossless
+		if set["quality"] {
+			params.Quality = f.quality
+		}
+		if set["effort"] {
+			params.Effort = f.effort
+		}
+		return params, nil
+	case vips.ImageTypeAVIF:
+		params := vips.NewAvifExportParams()
+		params.StripMetadata = f.strip
+		params.Lossless = f.lossless
+		if set["quality"] {
+			params.Quality = f.quality
+		}
+		if set["effort"] {
+			params.Effort = f.effort
+		}
+		return params, nil
+	case vips.ImageTypeTIFF:
+		params := vips.NewTiffExportParams()
+		params.StripMetadata = f.strip
+		if set["quality"] {
+			params.Quality = f.quality
+		}
+		return params, nil
+	case vips.ImageTypeGIF:
+		params := vips.NewGifExportParams()
+		params.StripMetadata = f.strip
+		if set["effort"] {
+			params.Effort = f.effort
+		}
+		return params, nil
+	case vips.ImageTypeJP2K:
+		params := vips.NewJp2kExportParams()
+		params.Lossless = f.lossless
+		if set["quality"] {
+			params.Quality = f.quality
+		}
+		return params, nil
+	case vips.ImageTypeJXL:
+		params := vips.NewJxlExportParams()
+		params.Lossless = f.lossless
+		if set["quality"] {
+			params.Quality = f.quality
+		}
+		if set["effort"] {
+			params.Effort = f.effort
+		}
+		return params, nil
+	default:
+		if f.format != "" {
+			return nil, fmt.Errorf("cannot write format %q: %w", f.format, vips.ErrUnsupportedSaveFormat)
+		}
+		return nil, fmt.Errorf("cannot determine output format of %q, use -format: %w", output, vips.ErrUnsupportedSaveFormat)
+	}
+}
+
+// thumbnailFlags are the LoadThumbnailFromFile flags shared by thumbnail and batch
+type thumbnailFlags struct {
+	width  int
+	height int
+	crop   string
+	size   string
+}
+
+// maxThumbnailDimension stands in for a width or height derived from the aspect ratio
+const maxThumbnailDimension = 10_000_000
+
+var cropNames = map[string]vips.Interesting{
+	"none":      vips.InterestingNone,
+	"centre":    vips.InterestingCentre,
+	"center":    vips.InterestingCentre,
+	"entropy":   vips.InterestingEntropy,
+	"attention": vips.InterestingAttention,
+	"low":       vips.InterestingLow,
+	"high":      vips.InterestingHigh,
+	"all":       vips.InterestingAll,
+}
+
+var sizeNames = map[string]vips.Size{
+	"both":  vips.SizeBoth,
+	"up":    vips.SizeUp,
+	"down":  vips.SizeDown,
+	"force": vips.SizeForce,
+}
+
+func (f *thumbnailFlags) register(fs *flag.FlagSet) {
+	fs.IntVar(&f.width, "width", 0, "Thumbnail width, derived from the aspect ratio if 0")
+	fs.IntVar(&f.height, "height", 0, "Thumbnail height, derived from the aspect ratio if 0")
+	fs.StringVar(&f.crop, "crop", "none", "Crop to exactly width x height around: none, centre, entropy, attention, low, high or all")
+	fs.StringVar(&f.size, "size", "both", "Allowed scaling: both, up, down or force")
+}
+
+// enabled reports whether a thumbnail size was given
+func (f *thumbnailFlags) enabled() bool {
+	return f.width > 0 || f.height > 0
+}
+
+// thumbnail holds validated thumbnailFlags
+type thumbnail struct {
+	width  int
+	height int
+	crop   vips.Interesting
+	size   vips.Size
+}
+
+func (f *thumbnailFlags) thumbnail() (*thumbnail, error) {
+	if f.width < 0 || f.height < 0 || !f.enabled() {
+		return nil, fmt.Errorf("thumbnail needs a positive -width or -height")
+	}
+	crop, ok := cropNames[f.crop]
+	if !ok {
+		return nil, fmt.Errorf("unknown crop %q", f.crop)
+	}
+	size, ok := sizeNames[f.size]
+	if !ok {
+		return nil, fmt.Errorf("unknown size %q", f.size)
+	}
+	// Without both dimensions the other one would be cropped or stretched to
+	// maxThumbnailDimension
+	if (crop != vips.InterestingNone || size == vips.SizeForce) && (f.width == 0 || f.height == 0) {
+		return nil, fmt.Errorf("-crop and -size force need both -width and -height")
+	}
+
+	t := &thumbnail{width: f.width, height: f.height, crop: crop, size: size}
+	if t.width == 0 {
+		t.width = maxThumbnailDimension
+	}
+	if t.height == 0 {
+		t.height = maxThumbnailDimension
+	}
+	return t, nil
+}
+
+// process loads input, as a thumbnail if t is not nil, and saves it to output
+func process(input, output string, imports *importFlags, exports *exportFlags, t *thumbnail) error {
+	params, err := exports.params(output)
+	if err != nil {
+		return err
+	}
+
+	var img *vips.ImageRef
+	if t != nil {
+		img, err = vips.LoadThumbnailFromFile(input, t.width, t.height, t.crop, t.size, imports.params())
+	} else {
+		img, err = vips.LoadImageFromFile(input, imports.params())
+	}
+	if err != nil {
+		return err
+	}
+	defer img.Close()
+
+	_, err = img.SaveToFile(output, params)
+	return err
+}
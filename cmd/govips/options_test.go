@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"path/filepath"
+	"testing"
+
+	"github.com/davidbyttow/govips/v2/vips"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThumbnailFlags(t *testing.T) {
+	f := thumbnailFlags{width: 300, crop: "none", size: "down"}
+	thumb, err := f.thumbnail()
+	require.NoError(t, err)
+	assert.Equal(t, &thumbnail{width: 300, height: maxThumbnailDimension, crop: vips.InterestingNone, size: vips.SizeDown}, thumb)
+
+	f = thumbnailFlags{width: 300, height: 200, crop: "centre", size: "force"}
+	thumb, err = f.thumbnail()
+	require.NoError(t, err)
+	assert.Equal(t, &thumbnail{width: 300, height: 200, crop: vips.InterestingCentre, size: vips.SizeForce}, thumb)
+}
+
+func TestThumbnailFlags_Invalid(t *testing.T) {
+	for _, f := range []thumbnailFlags{
+		{crop: "none", size: "both"},
+		{width: -1, height: 100, crop: "none", size: "both"},
+		{width: 100, crop: "middle", size: "both"},
+		{width: 100, crop: "none", size: "sideways"},
+		{width: 300, crop: "centre", size: "both"},
+		{height: 300, crop: "attention", size: "down"},
+		{width: 300, crop: "none", size: "force"},
+	} {
+		_, err := f.thumbnail()
+		assert.Error(t, err, "%+v", f)
+	}
+}
+
+func parseExportFlags(t *testing.T, args ...string) *exportFlags {
+	var f exportFlags
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	f.register(fs)
+	require.NoError(t, fs.Parse(args))
+	return &f
+}
+
+func TestExportFlags_Params(t *testing.T) {
+	params, err := parseExportFlags(t).params("out.jpg")
+	require.NoError(t, err)
+	assert.Equal(t, vips.NewJpegExportParams(), params)
+
+	params, err = parseExportFlags(t, "-quality", "50", "-interlace=false", "-strip").params("out.jpeg")
+	require.NoError(t, err)
+	jpeg := vips.NewJpegExportParams()
+	jpeg.Quality, jpeg.Interlace, jpeg.StripMetadata = 50, false, true
+	assert.Equal(t, jpeg, params)
+
+	params, err = parseExportFlags(t, "-compression", "9").params("out.PNG")
+	require.NoError(t, err)
+	png := vips.NewPngExportParams()
+	png.Compression = 9
+	assert.Equal(t, png, params)
+
+	// -format takes precedence over the extension
+	params, err = parseExportFlags(t, "-format", "webp", "-lossless", "-effort", "6").params("out.jpg")
+	require.NoError(t, err)
+	webp := vips.NewWebpExportParams()
+	webp.Lossless, webp.ReductionEffort = true, 6
+	assert.Equal(t, webp, params)
+}
+
+func TestExportFlags_Params__Unsupported(t *testing.T) {
+	_, err := parseExportFlags(t).params("out.txt")
+	assert.True(t, errors.Is(err, vips.ErrUnsupportedSaveFormat))
+
+	_, err = parseExportFlags(t, "-format", "svg").params("out.jpg")
+	assert.True(t, errors.Is(err, vips.ErrUnsupportedSaveFormat))
+}
+
+func TestProcess__MaxPixels(t *testing.T) {
+	require.NoError(t, vips.Startup(nil))
+
+	var imports importFlags
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	imports.register(fs)
+	require.NoError(t, fs.Parse([]string{"-max-pixels", "100"}))
+
+	// The limit applies to the source of thumbnails as well
+	thumb, err := (&thumbnailFlags{width: 5, crop: "none", size: "both"}).thumbnail()
+	require.NoError(t, err)
+	output := filepath.Join(t.TempDir(), "out.png")
+	for _, th := range []*thumbnail{nil, thumb} {
+		err := process("../../resources/jpg-24bit.jpg", output, &imports, parseExportFlags(t), th)
+		assert.True(t, errors.Is(err, vips.ErrImageTooLarge))
+	}
+}
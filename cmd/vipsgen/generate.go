@@ -670,8 +670,13 @@ func genGoFunc(w *bytes.Buffer, op OpDef) {
 					fmt.Fprintf(w, "\t\t\tcOpts.%s = (*C.double)(unsafe.Pointer(&opts.%s[0]))\n", argName, exportedName)
 					fmt.Fprintf(w, "\t\t\tcOpts.%s_n = C.int(len(opts.%s))\n", argName, exportedName)
 				case ArgTypeArrayInt:
-					fmt.Fprintf(w, "\t\t\tpinner.Pin(&opts.%s[0])\n", exportedName)
-					fmt.Fprintf(w, "\t\t\tcOpts.%s = (*C.int)(unsafe.Pointer(&opts.%s[0]))\n", argName, exportedName)
+					// Go int is wider than C int, so the values must be copied
+					fmt.Fprintf(w, "\t\t\ttmp_%s := make([]C.int, len(opts.%s))\n", argName, exportedName)
+					fmt.Fprintf(w, "\t\t\tfor i, v := range opts.%s {\n", exportedName)
+					fmt.Fprintf(w, "\t\t\t\ttmp_%s[i] = C.int(v)\n", argName)
+					fmt.Fprintf(w, "\t\t\t}\n")
+					fmt.Fprintf(w, "\t\t\tpinner.Pin(&tmp_%s[0])\n", argName)
+					fmt.Fprintf(w, "\t\t\tcOpts.%s = &tmp_%s[0]\n", argName, argName)
 					fmt.Fprintf(w, "\t\t\tcOpts.%s_n = C.int(len(opts.%s))\n", argName, exportedName)
 				case ArgTypeImage:
 					fmt.Fprintf(w, "\t\t\tcOpts.%s = opts.%s\n", argName, exportedName)
@@ -0,0 +1,404 @@
+// Package iiif serves images over the IIIF Image API 3.0 (https://iiif.io/api/image/3.0/)
+// using govips. The Handler answers requests of the form
+//
+//	{identifier}/{region}/{size}/{rotation}/{quality}.{format}
+//	{identifier}/info.json
+//
+// relative to where it is mounted, loading source images through a Resolver.
+// vips.Startup must be called before the handler serves requests.
+package iiif
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"image"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/davidbyttow/govips/v2/vips"
+)
+
+// Resolver returns the encoded source image with the given identifier. It returns
+// ErrNotFound when there is no such image.
+type Resolver func(ctx context.Context, identifier string) ([]byte, error)
+
+// FileResolver returns a Resolver which reads images from files below root, treating the
+// identifier as a slash separated path. Identifiers cannot escape root.
+func FileResolver(root string) Resolver {
+	return func(ctx context.Context, identifier string) ([]byte, error) {
+		name := filepath.Join(root, filepath.FromSlash(path.Clean("/"+identifier)))
+		buf, err := os.ReadFile(name)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return buf, err
+	}
+}
+
+// Options configure a Handler
+type Options struct {
+	// BaseURL is the URL the handler is mounted at, used for the ids in info.json. Defaults
+	// to the scheme, host and path prefix of each request.
+	BaseURL string
+	// TileSize is the tile width advertised in info.json. Defaults to DefaultTileSize.
+	TileSize int
+	// Limits bound the size of the images produced. Defaults to a MaxArea of DefaultMaxArea
+	// when no limit is set.
+	Limits
+}
+
+// DefaultMaxArea is the largest number of pixels in a response when Options sets no limits
+const DefaultMaxArea = 8192 * 8192
+
+// Handler is an http.Handler serving a IIIF Image API 3.0 level 2 image service
+type Handler struct {
+	resolver Resolver
+	options  Options
+}
+
+// NewHandler creates a Handler serving the images found by resolver
+func NewHandler(resolver Resolver, options *Options) *Handler {
+	h := &Handler{resolver: resolver}
+	if options != nil {
+		h.options = *options
+	}
+	if h.options.Limits == (Limits{}) {
+		h.options.MaxArea = DefaultMaxArea
+	}
+	return h
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	// Identifiers may contain escaped slashes, so split the path before unescaping it
+	segments := strings.Split(strings.TrimPrefix(r.URL.EscapedPath(), "/"), "/")
+	n := len(segments)
+
+	switch {
+	case n >= 2 && segments[n-1] == "info.json":
+		id, err := unescape(segments[:n-1])
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		h.serveInfo(w, r, id)
+	case n >= 5:
+		id, err := unescape(segments[:n-4])
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		params := make([]string, 4)
+		for i, segment := range segments[n-4:] {
+			if params[i], err = url.PathUnescape(segment); err != nil {
+				writeError(w, badRequest("invalid path"))
+				return
+			}
+		}
+		h.serveImage(w, r, id, params)
+	case segments[0] != "":
+		// The base URI of an image redirects to its information
+		http.Redirect(w, r, h.baseURL(r)+"/"+strings.Join(segments, "/")+"/info.json", http.StatusSeeOther)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *Handler) serveInfo(w http.ResponseWriter, r *http.Request, id string) {
+	img, err := h.load(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	defer img.Close()
+
+	info := NewInfo(h.baseURL(r)+"/"+url.PathEscape(id), img.Width(), img.Height(), h.options)
+	body, err := json.Marshal(info)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// info.json is plain JSON unless the client asks for JSON-LD
+	w.Header().Set("Vary", "Accept")
+	if strings.Contains(r.Header.Get("Accept"), "application/ld+json") {
+		w.Header().Set("Content-Type", InfoLDContentType)
+	} else {
+		w.Header().Set("Content-Type", InfoContentType)
+	}
+	writeBody(w, r, body)
+}
+
+func (h *Handler) serveImage(w http.ResponseWriter, r *http.Request, id string, params []string) {
+	req, err := ParseRequest(id, params)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if !vips.IsSaveTypeSupported(req.Format) {
+		writeError(w, badRequest("format %s is not supported", req.Format.MIMEType()))
+		return
+	}
+
+	body, err := h.render(r.Context(), req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", req.Format.MIMEType())
+	w.Header().Set("Link", `<`+ProfileURI+`>;rel="profile"`)
+	writeBody(w, r, body)
+}
+
+// render loads the source image of req and applies the region, size, rotation, quality and
+// format of the request, in that order
+func (h *Handler) render(ctx context.Context, req *Request) ([]byte, error) {
+	buf, err := h.resolver(ctx, req.Identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := vips.LoadImageFromBuffer(buf, vips.NewImportParams())
+	if err != nil {
+		return nil, err
+	}
+	defer func() { img.Close() }()
+
+	width, height := img.Width(), img.Height()
+	region, size, err := req.Resolve(width, height, h.options.Limits)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reload with shrink-on-load when the region is reduced by at least half
+	if params := shrinkOnLoad(img.Format(), region, size); params != nil {
+		shrunk, err := vips.LoadImageFromBuffer(buf, params)
+		if err != nil {
+			return nil, err
+		}
+		img.Close()
+		img = shrunk
+		region = scaleRegion(region, img.Width(), img.Height(), width, height)
+	}
+
+	if region != image.Rect(0, 0, img.Width(), img.Height()) {
+		if err := img.ExtractArea(region.Min.X, region.Min.Y, region.Dx(), region.Dy()); err != nil {
+			return nil, err
+		}
+	}
+	if size != region.Size() {
+		hScale := float64(size.X) / float64(region.Dx())
+		vScale := float64(size.Y) / float64(region.Dy())
+		if err := img.ResizeWithVScale(hScale, vScale, vips.KernelLanczos3); err != nil {
+			return nil, err
+		}
+		// Resizing may round up by a pixel
+		if img.Width() > size.X || img.Height() > size.Y {
+			if err := img.ExtractArea(0, 0, min(img.Width(), size.X), min(img.Height(), size.Y)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := rotate(img, req.Rotation, req.Format); err != nil {
+		return nil, err
+	}
+	if err := applyQuality(img, req.Quality); err != nil {
+		return nil, err
+	}
+	return export(ctx, img, req.Format)
+}
+
+func (h *Handler) load(ctx context.Context, id string) (*vips.ImageRef, error) {
+	buf, err := h.resolver(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return vips.LoadImageFromBuffer(buf, vips.NewImportParams())
+}
+
+// baseURL returns the URL the handler is mounted at, without a trailing slash
+func (h *Handler) baseURL(r *http.Request) string {
+	if h.options.BaseURL != "" {
+		return strings.TrimSuffix(h.options.BaseURL, "/")
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	// Under http.StripPrefix the request URI still holds the prefix removed from URL.Path
+	prefix := ""
+	if uri, err := url.ParseRequestURI(r.RequestURI); err == nil {
+		prefix = strings.TrimSuffix(uri.EscapedPath(), r.URL.EscapedPath())
+	}
+	return scheme + "://" + r.Host + strings.TrimSuffix(prefix, "/")
+}
+
+// shrinkOnLoad returns import params which shrink the source by a power of two no larger
+// than the reduction from region to size, or nil if the format cannot shrink on load
+func shrinkOnLoad(format vips.ImageType, region image.Rectangle, size image.Point) *vips.ImportParams {
+	reduction := math.Min(float64(region.Dx())/float64(size.X), float64(region.Dy())/float64(size.Y))
+
+	shrink := 1
+	for shrink < 8 && float64(shrink*2) <= reduction {
+		shrink *= 2
+	}
+	if shrink == 1 {
+		return nil
+	}
+
+	params := vips.NewImportParams()
+	switch format {
+	case vips.ImageTypeJPEG:
+		params.JpegShrinkFactor.Set(shrink)
+	case vips.ImageTypeWEBP:
+		params.WebpScaleFactor.Set(1 / float64(shrink))
+	default:
+		return nil
+	}
+	return params
+}
+
+// scaleRegion maps region of a width by height image onto the same image shrunk to
+// shrunkWidth by shrunkHeight
+func scaleRegion(region image.Rectangle, shrunkWidth, shrunkHeight, width, height int) image.Rectangle {
+	sx := float64(shrunkWidth) / float64(width)
+	sy := float64(shrunkHeight) / float64(height)
+
+	x := int(math.Floor(float64(region.Min.X) * sx))
+	y := int(math.Floor(float64(region.Min.Y) * sy))
+	w := roundDimension(float64(region.Dx()) * sx)
+	h := roundDimension(float64(region.Dy()) * sy)
+
+	bounds := image.Rect(0, 0, shrunkWidth, shrunkHeight)
+	scaled := image.Rect(x, y, x+w, y+h).Intersect(bounds)
+	if scaled.Empty() {
+		return bounds
+	}
+	return scaled
+}
+
+func rotate(img *vips.ImageRef, rotation Rotation, format vips.ImageType) error {
+	if rotation.Mirror {
+		if err := img.Flip(vips.DirectionHorizontal); err != nil {
+			return err
+		}
+	}
+
+	switch rotation.Degrees {
+	case 0:
+		return nil
+	case 90:
+		return img.Rotate(vips.Angle90)
+	case 180:
+		return img.Rotate(vips.Angle180)
+	case 270:
+		return img.Rotate(vips.Angle270)
+	}
+
+	// The corners uncovered by the rotation are transparent where the format allows it
+	background := &vips.ColorRGBA{R: 255, G: 255, B: 255, A: 255}
+	if format != vips.ImageTypeJPEG {
+		if !img.HasAlpha() {
+			if err := img.AddAlpha(); err != nil {
+				return err
+			}
+		}
+		background = &vips.ColorRGBA{}
+	}
+	return img.RotateArbitrary(rotation.Degrees, background, nil)
+}
+
+func applyQuality(img *vips.ImageRef, quality Quality) error {
+	switch quality {
+	case QualityColor:
+		return img.ToColorSpace(vips.InterpretationSRGB)
+	case QualityGray:
+		return img.ToColorSpace(vips.InterpretationBW)
+	case QualityBitonal:
+		if img.HasAlpha() {
+			if err := img.Flatten(&vips.Color{R: 255, G: 255, B: 255}); err != nil {
+				return err
+			}
+		}
+		if err := img.ToColorSpace(vips.InterpretationBW); err != nil {
+			return err
+		}
+		// Map 127 and below to black and everything else to white, clipped by the cast
+		if err := img.Linear([]float64{255}, []float64{-127 * 255}); err != nil {
+			return err
+		}
+		return img.Cast(vips.BandFormatUchar)
+	default:
+		if interpretation := img.Interpretation(); interpretation != vips.InterpretationSRGB && interpretation != vips.InterpretationBW {
+			return img.ToColorSpace(vips.InterpretationSRGB)
+		}
+		return nil
+	}
+}
+
+func export(ctx context.Context, img *vips.ImageRef, format vips.ImageType) ([]byte, error) {
+	var buf []byte
+	var err error
+	switch format {
+	case vips.ImageTypePNG:
+		buf, _, err = img.ExportPngContext(ctx, vips.NewPngExportParams())
+	case vips.ImageTypeWEBP:
+		buf, _, err = img.ExportWebpContext(ctx, vips.NewWebpExportParams())
+	case vips.ImageTypeGIF:
+		buf, _, err = img.ExportGIFContext(ctx, vips.NewGifExportParams())
+	case vips.ImageTypeTIFF:
+		buf, _, err = img.ExportTiffContext(ctx, vips.NewTiffExportParams())
+	case vips.ImageTypeJP2K:
+		buf, _, err = img.ExportJp2kContext(ctx, vips.NewJp2kExportParams())
+	default:
+		buf, _, err = img.ExportJpegContext(ctx, vips.NewJpegExportParams())
+	}
+	return buf, err
+}
+
+func unescape(segments []string) (string, error) {
+	id, err := url.PathUnescape(strings.Join(segments, "/"))
+	if err != nil || id == "" {
+		return "", badRequest("invalid identifier")
+	}
+	return id, nil
+}
+
+func writeBody(w http.ResponseWriter, r *http.Request, body []byte) {
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(body)
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrBadRequest):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+	default:
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
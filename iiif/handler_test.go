@@ -0,0 +1,165 @@
+package iiif
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/davidbyttow/govips/v2/vips"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const resources = "../resources/"
+
+func serve(t *testing.T, handler http.Handler, target string) *httptest.ResponseRecorder {
+	require.NoError(t, vips.Startup(nil))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	return rec
+}
+
+func TestHandler_Info(t *testing.T) {
+	handler := http.StripPrefix("/iiif", NewHandler(FileResolver(resources), nil))
+
+	rec := serve(t, handler, "/iiif/png-24bit.png/info.json")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "*", rec.Header().Get("Access-Control-Allow-Origin"))
+
+	var info Info
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &info))
+	assert.Equal(t, "http://example.com/iiif/png-24bit.png", info.ID)
+	assert.Equal(t, 1920, info.Width)
+	assert.Equal(t, 1080, info.Height)
+	assert.Equal(t, []int{1, 2, 4}, info.Tiles[0].ScaleFactors)
+	assert.Equal(t, DefaultMaxArea, info.MaxArea)
+}
+
+func TestHandler_InfoJSONLD(t *testing.T) {
+	require.NoError(t, vips.Startup(nil))
+	handler := NewHandler(FileResolver(resources), nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/png-24bit.png/info.json", nil)
+	req.Header.Set("Accept", `application/ld+json;profile="`+ContextURI+`"`)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, InfoLDContentType, rec.Header().Get("Content-Type"))
+	assert.Equal(t, "Accept", rec.Header().Get("Vary"))
+}
+
+func TestHandler_InfoRedirect(t *testing.T) {
+	handler := NewHandler(FileResolver(resources), &Options{BaseURL: "https://images.example.com/iiif/"})
+
+	rec := serve(t, handler, "/png-24bit.png")
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, "https://images.example.com/iiif/png-24bit.png/info.json", rec.Header().Get("Location"))
+}
+
+func TestHandler_Image(t *testing.T) {
+	handler := NewHandler(FileResolver(resources), nil)
+
+	rec := serve(t, handler, "/jpg-24bit.jpg/full/max/0/default.jpg")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "image/jpeg", rec.Header().Get("Content-Type"))
+
+	source, err := vips.NewImageFromFile(resources + "jpg-24bit.jpg")
+	require.NoError(t, err)
+	defer source.Close()
+
+	img, err := vips.NewImageFromBuffer(rec.Body.Bytes())
+	require.NoError(t, err)
+	defer img.Close()
+	assert.Equal(t, source.Width(), img.Width())
+	assert.Equal(t, source.Height(), img.Height())
+}
+
+func TestHandler_ImageOperations(t *testing.T) {
+	handler := NewHandler(FileResolver(resources), nil)
+
+	tests := []struct {
+		path          string
+		format        vips.ImageType
+		width, height int
+	}{
+		{"/png-24bit.png/0,0,960,540/480,/0/default.png", vips.ImageTypePNG, 480, 270},
+		{"/png-24bit.png/square/!100,100/90/default.webp", vips.ImageTypeWEBP, 100, 100},
+		{"/png-24bit.png/full/pct:10/!270/gray.png", vips.ImageTypePNG, 108, 192},
+		{"/png-24bit.png/pct:0,0,50,50/^1920,/180/color.jpg", vips.ImageTypeJPEG, 1920, 1080},
+		// Shrink-on-load for JPEG sources
+		{"/jpg-24bit.jpg/full/pct:10/0/bitonal.png", vips.ImageTypePNG, 10, 10},
+		{"/jpg-24bit.jpg/25,25,50,50/pct:20/0/default.png", vips.ImageTypePNG, 10, 10},
+	}
+	for _, test := range tests {
+		rec := serve(t, handler, test.path)
+		require.Equal(t, http.StatusOK, rec.Code, test.path)
+		assert.Equal(t, test.format.MIMEType(), rec.Header().Get("Content-Type"), test.path)
+
+		img, err := vips.NewImageFromBuffer(rec.Body.Bytes())
+		require.NoError(t, err, test.path)
+		assert.Equal(t, test.width, img.Width(), test.path)
+		assert.Equal(t, test.height, img.Height(), test.path)
+		img.Close()
+	}
+}
+
+func TestHandler_ImageArbitraryRotation(t *testing.T) {
+	handler := NewHandler(FileResolver(resources), nil)
+
+	rec := serve(t, handler, "/png-24bit.png/full/200,/45/default.png")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	img, err := vips.NewImageFromBuffer(rec.Body.Bytes())
+	require.NoError(t, err)
+	defer img.Close()
+	assert.True(t, img.HasAlpha())
+	assert.Greater(t, img.Width(), 200)
+}
+
+func TestHandler_Errors(t *testing.T) {
+	handler := NewHandler(FileResolver(resources), &Options{Limits: Limits{MaxWidth: 1000}})
+
+	tests := map[string]int{
+		"/missing.jpg/info.json":                          http.StatusNotFound,
+		"/missing.jpg/full/max/0/default.jpg":             http.StatusNotFound,
+		"/../go.mod/info.json":                            http.StatusNotFound,
+		"/png-24bit.png/full/max/0/default.bmp":           http.StatusBadRequest,
+		"/png-24bit.png/2000,0,10,10/max/0/default.jpg":   http.StatusBadRequest,
+		"/png-24bit.png/full/^2000,/0/default.jpg":        http.StatusBadRequest,
+		"/png-24bit.png/full/1200,/0/default.jpg":         http.StatusBadRequest,
+		"/png-24bit.png/0,0,10,1000/^,1001/0/default.jpg": http.StatusBadRequest,
+	}
+	for path, code := range tests {
+		rec := serve(t, handler, path)
+		assert.Equal(t, code, rec.Code, path)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/png-24bit.png/info.json", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestHandler_DefaultLimits(t *testing.T) {
+	handler := NewHandler(FileResolver(resources), nil)
+
+	for _, path := range []string{
+		"/png-24bit.png/full/^pct:100000/0/default.jpg",
+		"/png-24bit.png/full/^2000000,/0/default.jpg",
+	} {
+		rec := serve(t, handler, path)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, path)
+	}
+}
+
+func TestHandler_Head(t *testing.T) {
+	require.NoError(t, vips.Startup(nil))
+	handler := NewHandler(FileResolver(resources), nil)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/png-24bit.png/full/100,/0/default.png", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("Content-Length"))
+	assert.Equal(t, 0, rec.Body.Len())
+}
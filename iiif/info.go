@@ -0,0 +1,112 @@
+package iiif
+
+import (
+	"image"
+	"math"
+	"sort"
+
+	"github.com/davidbyttow/govips/v2/vips"
+)
+
+// IIIF Image API 3.0 identifiers
+const (
+	ContextURI  = "http://iiif.io/api/image/3/context.json"
+	ProtocolURI = "http://iiif.io/api/image"
+	ProfileURI  = "http://iiif.io/api/image/3/level2.json"
+	// InfoContentType is the media type of info.json responses
+	InfoContentType = "application/json"
+	// InfoLDContentType is the media type of info.json responses to clients accepting JSON-LD
+	InfoLDContentType = `application/ld+json;profile="` + ContextURI + `"`
+)
+
+// DefaultTileSize is the tile width advertised in info.json when Options.TileSize is zero
+const DefaultTileSize = 512
+
+// Info is the image information document served as info.json
+type Info struct {
+	Context        string     `json:"@context"`
+	ID             string     `json:"id"`
+	Type           string     `json:"type"`
+	Protocol       string     `json:"protocol"`
+	Profile        string     `json:"profile"`
+	Width          int        `json:"width"`
+	Height         int        `json:"height"`
+	MaxWidth       int        `json:"maxWidth,omitempty"`
+	MaxHeight      int        `json:"maxHeight,omitempty"`
+	MaxArea        int        `json:"maxArea,omitempty"`
+	Sizes          []InfoSize `json:"sizes,omitempty"`
+	Tiles          []InfoTile `json:"tiles,omitempty"`
+	ExtraQualities []string   `json:"extraQualities,omitempty"`
+	ExtraFormats   []string   `json:"extraFormats,omitempty"`
+	ExtraFeatures  []string   `json:"extraFeatures,omitempty"`
+}
+
+// InfoSize is a size of the full image which clients are encouraged to request
+type InfoSize struct {
+	Type   string `json:"type"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// InfoTile describes the square tiles clients should request at each scale factor
+type InfoTile struct {
+	Type         string `json:"type"`
+	Width        int    `json:"width"`
+	ScaleFactors []int  `json:"scaleFactors"`
+}
+
+// NewInfo creates the information document of a width by height image. id is the base URI
+// of the image, without the trailing /info.json.
+func NewInfo(id string, width, height int, options Options) *Info {
+	tileSize := options.TileSize
+	if tileSize <= 0 {
+		tileSize = DefaultTileSize
+	}
+
+	info := &Info{
+		Context:        ContextURI,
+		ID:             id,
+		Type:           "ImageService3",
+		Protocol:       ProtocolURI,
+		Profile:        "level2",
+		Width:          width,
+		Height:         height,
+		MaxWidth:       options.MaxWidth,
+		MaxHeight:      options.MaxHeight,
+		MaxArea:        options.MaxArea,
+		ExtraQualities: []string{string(QualityGray), string(QualityBitonal)},
+		ExtraFormats:   extraFormats(),
+		ExtraFeatures:  []string{"mirroring", "rotationArbitrary", "sizeUpscaling"},
+	}
+
+	// Halve the image until it fits in a single tile
+	tile := InfoTile{Type: "Tile", Width: tileSize}
+	for factor := 1; ; factor *= 2 {
+		tile.ScaleFactors = append(tile.ScaleFactors, factor)
+		w := int(math.Ceil(float64(width) / float64(factor)))
+		h := int(math.Ceil(float64(height) / float64(factor)))
+		if factor > 1 && options.Limits.allows(image.Pt(w, h)) {
+			info.Sizes = append(info.Sizes, InfoSize{Type: "Size", Width: w, Height: h})
+		}
+		if w <= tileSize && h <= tileSize {
+			break
+		}
+	}
+	info.Tiles = []InfoTile{tile}
+
+	// Sizes are listed smallest first
+	sort.Slice(info.Sizes, func(i, j int) bool { return info.Sizes[i].Width < info.Sizes[j].Width })
+	return info
+}
+
+// extraFormats lists the formats beyond the required jpg and png which libvips can save
+func extraFormats() []string {
+	var extra []string
+	for name, imageType := range formats {
+		if imageType != vips.ImageTypeJPEG && imageType != vips.ImageTypePNG && vips.IsSaveTypeSupported(imageType) {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	return extra
+}
@@ -0,0 +1,412 @@
+package iiif
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/davidbyttow/govips/v2/vips"
+)
+
+var (
+	// ErrBadRequest is wrapped by errors for requests which are malformed or cannot be
+	// satisfied for the image, which the Handler answers with 400 Bad Request
+	ErrBadRequest = errors.New("iiif: bad request")
+	// ErrNotFound is returned by a Resolver when no image has the identifier, which the
+	// Handler answers with 404 Not Found
+	ErrNotFound = errors.New("iiif: image not found")
+)
+
+func badRequest(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrBadRequest, fmt.Sprintf(format, args...))
+}
+
+// RegionKind is the form of the region parameter of a request
+type RegionKind int
+
+// RegionKind enum
+const (
+	RegionFull RegionKind = iota
+	RegionSquare
+	RegionPixels
+	RegionPercent
+)
+
+// Region is the rectangular portion of the image requested, e.g. "full", "square",
+// "125,15,120,140" or "pct:41.6,7.5,40,70"
+type Region struct {
+	Kind       RegionKind
+	X, Y, W, H float64
+}
+
+// SizeKind is the form of the size parameter of a request
+type SizeKind int
+
+// SizeKind enum
+const (
+	// SizeMax is "max", the region at its full size or the largest size allowed
+	SizeMax SizeKind = iota
+	// SizeWidth is "w,", scaling to the width and keeping the aspect ratio
+	SizeWidth
+	// SizeHeight is ",h", scaling to the height and keeping the aspect ratio
+	SizeHeight
+	// SizePercent is "pct:n", scaling both dimensions by n percent
+	SizePercent
+	// SizeExact is "w,h", scaling to exactly w by h, distorting if needed
+	SizeExact
+	// SizeBestFit is "!w,h", the largest size within w by h which keeps the aspect ratio
+	SizeBestFit
+)
+
+// Size is the dimensions the region is scaled to. Upscale is set by the "^" prefix which
+// allows a size larger than the region.
+type Size struct {
+	Kind    SizeKind
+	Upscale bool
+	Width   int
+	Height  int
+	Percent float64
+}
+
+// Rotation is the clockwise rotation in degrees applied after scaling. Mirror is set by
+// the "!" prefix and flips the image horizontally before it is rotated.
+type Rotation struct {
+	Degrees float64
+	Mirror  bool
+}
+
+// Quality is the colour quality of the response
+type Quality string
+
+// Quality enum
+const (
+	QualityDefault Quality = "default"
+	QualityColor   Quality = "color"
+	QualityGray    Quality = "gray"
+	QualityBitonal Quality = "bitonal"
+)
+
+// formats maps the format parameter of a request to the image type it is encoded as
+var formats = map[string]vips.ImageType{
+	"jpg":  vips.ImageTypeJPEG,
+	"png":  vips.ImageTypePNG,
+	"webp": vips.ImageTypeWEBP,
+	"gif":  vips.ImageTypeGIF,
+	"tif":  vips.ImageTypeTIFF,
+	"jp2":  vips.ImageTypeJP2K,
+}
+
+// Request is a parsed IIIF Image API 3.0 image request:
+// {identifier}/{region}/{size}/{rotation}/{quality}.{format}
+type Request struct {
+	Identifier string
+	Region     Region
+	Size       Size
+	Rotation   Rotation
+	Quality    Quality
+	Format     vips.ImageType
+}
+
+// Limits are the largest responses a server produces. Zero means no limit, except that
+// MaxHeight defaults to MaxWidth as clients infer from info.json. Without any limits sizes
+// cannot be upscaled beyond the region.
+type Limits struct {
+	MaxWidth  int
+	MaxHeight int
+	MaxArea   int
+}
+
+// ParseRequest parses the image request parameters that follow the identifier in a path:
+// region, size, rotation and "quality.format".
+func ParseRequest(identifier string, params []string) (*Request, error) {
+	if len(params) != 4 {
+		return nil, badRequest("expected region, size, rotation and quality.format")
+	}
+
+	req := &Request{Identifier: identifier}
+	var err error
+	if req.Region, err = parseRegion(params[0]); err != nil {
+		return nil, err
+	}
+	if req.Size, err = parseSize(params[1]); err != nil {
+		return nil, err
+	}
+	if req.Rotation, err = parseRotation(params[2]); err != nil {
+		return nil, err
+	}
+
+	quality, format, ok := cutLast(params[3], ".")
+	if !ok {
+		return nil, badRequest("missing format in %q", params[3])
+	}
+	switch req.Quality = Quality(quality); req.Quality {
+	case QualityDefault, QualityColor, QualityGray, QualityBitonal:
+	default:
+		return nil, badRequest("unknown quality %q", quality)
+	}
+	if req.Format, ok = formats[format]; !ok {
+		return nil, badRequest("unknown format %q", format)
+	}
+	return req, nil
+}
+
+func parseRegion(s string) (Region, error) {
+	switch s {
+	case "full":
+		return Region{Kind: RegionFull}, nil
+	case "square":
+		return Region{Kind: RegionSquare}, nil
+	}
+
+	region := Region{Kind: RegionPixels}
+	if rest, ok := strings.CutPrefix(s, "pct:"); ok {
+		region.Kind = RegionPercent
+		s = rest
+	}
+
+	values, err := parseFloats(s, 4)
+	if err != nil {
+		return Region{}, badRequest("invalid region %q", s)
+	}
+	region.X, region.Y, region.W, region.H = values[0], values[1], values[2], values[3]
+
+	if region.Kind == RegionPixels {
+		for _, v := range values {
+			if v != math.Trunc(v) {
+				return Region{}, badRequest("region %q must be whole pixels", s)
+			}
+		}
+	}
+	if region.X < 0 || region.Y < 0 || region.W <= 0 || region.H <= 0 {
+		return Region{}, badRequest("invalid region %q", s)
+	}
+	return region, nil
+}
+
+func parseSize(s string) (Size, error) {
+	var size Size
+	s, size.Upscale = strings.CutPrefix(s, "^")
+
+	if s == "max" {
+		size.Kind = SizeMax
+		return size, nil
+	}
+
+	if rest, ok := strings.CutPrefix(s, "pct:"); ok {
+		pct, err := strconv.ParseFloat(rest, 64)
+		if err != nil || !(pct > 0) || math.IsInf(pct, 0) {
+			return Size{}, badRequest("invalid size %q", s)
+		}
+		if pct > 100 && !size.Upscale {
+			return Size{}, badRequest("size %q larger than 100%% needs the ^ prefix", s)
+		}
+		size.Kind, size.Percent = SizePercent, pct
+		return size, nil
+	}
+
+	s, bestFit := strings.CutPrefix(s, "!")
+	w, h, ok := strings.Cut(s, ",")
+	if !ok {
+		return Size{}, badRequest("invalid size %q", s)
+	}
+
+	var err error
+	if size.Width, err = parseDimension(w); err != nil {
+		return Size{}, badRequest("invalid size %q", s)
+	}
+	if size.Height, err = parseDimension(h); err != nil {
+		return Size{}, badRequest("invalid size %q", s)
+	}
+
+	switch {
+	case bestFit && size.Width > 0 && size.Height > 0:
+		size.Kind = SizeBestFit
+	case bestFit:
+		return Size{}, badRequest("size %q needs a width and a height", s)
+	case size.Width > 0 && size.Height > 0:
+		size.Kind = SizeExact
+	case size.Width > 0:
+		size.Kind = SizeWidth
+	case size.Height > 0:
+		size.Kind = SizeHeight
+	default:
+		return Size{}, badRequest("invalid size %q", s)
+	}
+	return size, nil
+}
+
+// parseDimension parses one side of a "w,h" size, where an empty string means unspecified
+func parseDimension(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v <= 0 {
+		return 0, errors.New("invalid dimension")
+	}
+	return v, nil
+}
+
+func parseRotation(s string) (Rotation, error) {
+	var rotation Rotation
+	s, rotation.Mirror = strings.CutPrefix(s, "!")
+
+	degrees, err := strconv.ParseFloat(s, 64)
+	if err != nil || !(degrees >= 0 && degrees <= 360) {
+		return Rotation{}, badRequest("invalid rotation %q", s)
+	}
+	rotation.Degrees = math.Mod(degrees, 360)
+	return rotation, nil
+}
+
+// Resolve returns the region of a width by height image selected by the request, in pixels,
+// and the size it is scaled to. Regions extending beyond the image are cropped to it.
+func (r *Request) Resolve(width, height int, limits Limits) (image.Rectangle, image.Point, error) {
+	region, err := r.Region.resolve(width, height)
+	if err != nil {
+		return image.Rectangle{}, image.Point{}, err
+	}
+
+	size, err := r.Size.resolve(region.Dx(), region.Dy(), limits)
+	if err != nil {
+		return image.Rectangle{}, image.Point{}, err
+	}
+	return region, size, nil
+}
+
+func (g Region) resolve(width, height int) (image.Rectangle, error) {
+	bounds := image.Rect(0, 0, width, height)
+
+	var rect image.Rectangle
+	switch g.Kind {
+	case RegionFull:
+		return bounds, nil
+	case RegionSquare:
+		side := min(width, height)
+		x, y := (width-side)/2, (height-side)/2
+		return image.Rect(x, y, x+side, y+side), nil
+	case RegionPixels:
+		rect = image.Rect(int(g.X), int(g.Y), int(g.X+g.W), int(g.Y+g.H))
+	case RegionPercent:
+		x := int(math.Floor(g.X * float64(width) / 100))
+		y := int(math.Floor(g.Y * float64(height) / 100))
+		w := int(math.Round(g.W * float64(width) / 100))
+		h := int(math.Round(g.H * float64(height) / 100))
+		rect = image.Rect(x, y, x+w, y+h)
+	}
+
+	rect = rect.Intersect(bounds)
+	if rect.Empty() {
+		return image.Rectangle{}, badRequest("region is outside the image")
+	}
+	return rect, nil
+}
+
+func (s Size) resolve(width, height int, limits Limits) (image.Point, error) {
+	w, h := float64(width), float64(height)
+	aspect := w / h
+
+	var out image.Point
+	switch s.Kind {
+	case SizeMax:
+		// With the ^ prefix max is as large as the limits allow
+		if s.Upscale && (limits.MaxWidth > 0 || limits.MaxHeight > 0 || limits.MaxArea > 0) {
+			w, h = math.Inf(1), math.Inf(1)
+		}
+		return limits.fit(w, h, aspect), nil
+	case SizeWidth:
+		out = image.Pt(s.Width, roundDimension(float64(s.Width)/aspect))
+	case SizeHeight:
+		out = image.Pt(roundDimension(float64(s.Height)*aspect), s.Height)
+	case SizePercent:
+		out = image.Pt(roundDimension(w*s.Percent/100), roundDimension(h*s.Percent/100))
+	case SizeExact:
+		out = image.Pt(s.Width, s.Height)
+	case SizeBestFit:
+		scale := math.Min(float64(s.Width)/w, float64(s.Height)/h)
+		out = image.Pt(roundDimension(w*scale), roundDimension(h*scale))
+	}
+
+	if out.X > width || out.Y > height {
+		if !s.Upscale {
+			return image.Point{}, badRequest("size %dx%d is larger than the region and needs the ^ prefix", out.X, out.Y)
+		}
+		if limits == (Limits{}) {
+			return image.Point{}, badRequest("size %dx%d is larger than the region and the server has no limits", out.X, out.Y)
+		}
+	}
+	if !limits.allows(out) {
+		return image.Point{}, badRequest("size %dx%d exceeds the server limits", out.X, out.Y)
+	}
+	return out, nil
+}
+
+func (l Limits) allows(size image.Point) bool {
+	maxHeight := l.maxHeight()
+	return (l.MaxWidth <= 0 || size.X <= l.MaxWidth) &&
+		(maxHeight <= 0 || size.Y <= maxHeight) &&
+		(l.MaxArea <= 0 || size.X*size.Y <= l.MaxArea)
+}
+
+// maxHeight returns MaxHeight, or MaxWidth when only that is set
+func (l Limits) maxHeight() int {
+	if l.MaxHeight <= 0 {
+		return l.MaxWidth
+	}
+	return l.MaxHeight
+}
+
+// fit scales a w by h size down, keeping the aspect ratio, until it is within the limits
+func (l Limits) fit(w, h, aspect float64) image.Point {
+	if l.MaxWidth > 0 && w > float64(l.MaxWidth) {
+		w, h = float64(l.MaxWidth), float64(l.MaxWidth)/aspect
+	}
+	if maxHeight := l.maxHeight(); maxHeight > 0 && h > float64(maxHeight) {
+		w, h = float64(maxHeight)*aspect, float64(maxHeight)
+	}
+	if l.MaxArea > 0 && w*h > float64(l.MaxArea) {
+		h = math.Sqrt(float64(l.MaxArea) / aspect)
+		w = h * aspect
+	}
+
+	// Rounding may overshoot the limits by a pixel
+	out := image.Pt(roundDimension(w), roundDimension(h))
+	for !l.allows(out) && out.X > 1 {
+		out.X--
+		out.Y = roundDimension(float64(out.X) / aspect)
+	}
+	return out
+}
+
+// roundDimension rounds a scaled dimension to whole pixels, keeping at least one
+func roundDimension(v float64) int {
+	return max(1, int(math.Round(v)))
+}
+
+func parseFloats(s string, n int) ([]float64, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != n {
+		return nil, errors.New("wrong number of values")
+	}
+
+	values := make([]float64, n)
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, errors.New("invalid number")
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func cutLast(s, sep string) (string, string, bool) {
+	i := strings.LastIndex(s, sep)
+	if i < 0 {
+		return s, "", false
+	}
+	return s[:i], s[i+len(sep):], true
+}
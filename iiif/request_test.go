@@ -0,0 +1,158 @@
+package iiif
+
+import (
+	"errors"
+	"image"
+	"strings"
+	"testing"
+
+	"github.com/davidbyttow/govips/v2/vips"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parse(path string) (*Request, error) {
+	return ParseRequest("id", strings.Split(path, "/"))
+}
+
+func TestParseRequest(t *testing.T) {
+	req, err := parse("pct:10,20.5,30,40/^!200,100/!90/gray.png")
+	require.NoError(t, err)
+	assert.Equal(t, "id", req.Identifier)
+	assert.Equal(t, Region{Kind: RegionPercent, X: 10, Y: 20.5, W: 30, H: 40}, req.Region)
+	assert.Equal(t, Size{Kind: SizeBestFit, Upscale: true, Width: 200, Height: 100}, req.Size)
+	assert.Equal(t, Rotation{Degrees: 90, Mirror: true}, req.Rotation)
+	assert.Equal(t, QualityGray, req.Quality)
+	assert.Equal(t, vips.ImageTypePNG, req.Format)
+
+	req, err = parse("full/max/360/default.jpg")
+	require.NoError(t, err)
+	assert.Equal(t, Region{Kind: RegionFull}, req.Region)
+	assert.Equal(t, Size{Kind: SizeMax}, req.Size)
+	assert.Equal(t, Rotation{}, req.Rotation)
+	assert.Equal(t, vips.ImageTypeJPEG, req.Format)
+}
+
+func TestParseRequest_Sizes(t *testing.T) {
+	tests := map[string]Size{
+		"max":      {Kind: SizeMax},
+		"^max":     {Kind: SizeMax, Upscale: true},
+		"150,":     {Kind: SizeWidth, Width: 150},
+		",150":     {Kind: SizeHeight, Height: 150},
+		"pct:50":   {Kind: SizePercent, Percent: 50},
+		"^pct:120": {Kind: SizePercent, Upscale: true, Percent: 120},
+		"225,100":  {Kind: SizeExact, Width: 225, Height: 100},
+		"!225,100": {Kind: SizeBestFit, Width: 225, Height: 100},
+	}
+	for s, expected := range tests {
+		req, err := parse("full/" + s + "/0/default.jpg")
+		require.NoError(t, err, s)
+		assert.Equal(t, expected, req.Size, s)
+	}
+}
+
+func TestParseRequest_Invalid(t *testing.T) {
+	for _, path := range []string{
+		"full/max/0/default",
+		"full/max/0/default.bmp",
+		"full/max/0/sepia.jpg",
+		"whole/max/0/default.jpg",
+		"0,0,10/max/0/default.jpg",
+		"0,0,0,10/max/0/default.jpg",
+		"-1,0,10,10/max/0/default.jpg",
+		"0.5,0,10,10/max/0/default.jpg",
+		"pct:0,0,0,10/max/0/default.jpg",
+		"full/pct:120/0/default.jpg",
+		"full/pct:0/0/default.jpg",
+		"full/!100,/0/default.jpg",
+		"full/,/0/default.jpg",
+		"full/0,10/0/default.jpg",
+		"full/big/0/default.jpg",
+		"full/max/361/default.jpg",
+		"full/max/-90/default.jpg",
+		"full/max/NaN/default.jpg",
+		"full/max/0",
+	} {
+		_, err := parse(path)
+		assert.True(t, errors.Is(err, ErrBadRequest), path)
+	}
+}
+
+func TestRequest_Resolve(t *testing.T) {
+	tests := []struct {
+		path   string
+		limits Limits
+		region image.Rectangle
+		size   image.Point
+	}{
+		{"full/max/0/default.jpg", Limits{}, image.Rect(0, 0, 300, 200), image.Pt(300, 200)},
+		{"square/max/0/default.jpg", Limits{}, image.Rect(50, 0, 250, 200), image.Pt(200, 200)},
+		{"125,15,120,140/max/0/default.jpg", Limits{}, image.Rect(125, 15, 245, 155), image.Pt(120, 140)},
+		{"250,150,100,100/max/0/default.jpg", Limits{}, image.Rect(250, 150, 300, 200), image.Pt(50, 50)},
+		{"pct:50,50,50,50/max/0/default.jpg", Limits{}, image.Rect(150, 100, 300, 200), image.Pt(150, 100)},
+		{"full/150,/0/default.jpg", Limits{}, image.Rect(0, 0, 300, 200), image.Pt(150, 100)},
+		{"full/,50/0/default.jpg", Limits{}, image.Rect(0, 0, 300, 200), image.Pt(75, 50)},
+		{"full/pct:25/0/default.jpg", Limits{}, image.Rect(0, 0, 300, 200), image.Pt(75, 50)},
+		{"full/100,100/0/default.jpg", Limits{}, image.Rect(0, 0, 300, 200), image.Pt(100, 100)},
+		{"full/!150,150/0/default.jpg", Limits{}, image.Rect(0, 0, 300, 200), image.Pt(150, 100)},
+		{"full/^600,/0/default.jpg", Limits{MaxArea: 240000}, image.Rect(0, 0, 300, 200), image.Pt(600, 400)},
+		{"full/^150,/0/default.jpg", Limits{}, image.Rect(0, 0, 300, 200), image.Pt(150, 100)},
+		{"full/max/0/default.jpg", Limits{MaxWidth: 150}, image.Rect(0, 0, 300, 200), image.Pt(150, 100)},
+		{"full/max/0/default.jpg", Limits{MaxArea: 15000}, image.Rect(0, 0, 300, 200), image.Pt(150, 100)},
+		{"full/^max/0/default.jpg", Limits{MaxHeight: 400}, image.Rect(0, 0, 300, 200), image.Pt(600, 400)},
+		{"full/^max/0/default.jpg", Limits{MaxWidth: 400}, image.Rect(0, 0, 300, 200), image.Pt(400, 267)},
+		{"full/^max/0/default.jpg", Limits{}, image.Rect(0, 0, 300, 200), image.Pt(300, 200)},
+	}
+	for _, test := range tests {
+		req, err := parse(test.path)
+		require.NoError(t, err, test.path)
+
+		region, size, err := req.Resolve(300, 200, test.limits)
+		require.NoError(t, err, test.path)
+		assert.Equal(t, test.region, region, test.path)
+		assert.Equal(t, test.size, size, test.path)
+	}
+}
+
+func TestRequest_Resolve_Invalid(t *testing.T) {
+	tests := []struct {
+		path   string
+		limits Limits
+	}{
+		{"300,0,10,10/max/0/default.jpg", Limits{}},
+		{"pct:100,0,10,10/max/0/default.jpg", Limits{}},
+		{"full/600,/0/default.jpg", Limits{}},
+		{"full/301,200/0/default.jpg", Limits{}},
+		{"full/!600,600/0/default.jpg", Limits{}},
+		{"full/200,/0/default.jpg", Limits{MaxWidth: 100}},
+		{"full/^600,/0/default.jpg", Limits{MaxArea: 100000}},
+		{"full/^600,/0/default.jpg", Limits{}},
+		{"full/^pct:100000/0/default.jpg", Limits{}},
+		{"0,0,10,200/^,250/0/default.jpg", Limits{MaxWidth: 200}},
+	}
+	for _, test := range tests {
+		req, err := parse(test.path)
+		require.NoError(t, err, test.path)
+
+		_, _, err = req.Resolve(300, 200, test.limits)
+		assert.True(t, errors.Is(err, ErrBadRequest), test.path)
+	}
+}
+
+func TestNewInfo(t *testing.T) {
+	info := NewInfo("https://example.com/iiif/plan", 2000, 1000, Options{TileSize: 256, Limits: Limits{MaxWidth: 1000}})
+	assert.Equal(t, ContextURI, info.Context)
+	assert.Equal(t, "ImageService3", info.Type)
+	assert.Equal(t, "level2", info.Profile)
+	assert.Equal(t, 1000, info.MaxWidth)
+
+	require.Len(t, info.Tiles, 1)
+	assert.Equal(t, 256, info.Tiles[0].Width)
+	assert.Equal(t, []int{1, 2, 4, 8}, info.Tiles[0].ScaleFactors)
+
+	assert.Equal(t, []InfoSize{
+		{Type: "Size", Width: 250, Height: 125},
+		{Type: "Size", Width: 500, Height: 250},
+		{Type: "Size", Width: 1000, Height: 500},
+	}, info.Sizes)
+}
@@ -0,0 +1,199 @@
+package imageserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Defaults used by HTTPFetcher when the matching field is zero
+const (
+	DefaultMaxSourceBytes = 50 << 20
+	DefaultFetchTimeout   = 30 * time.Second
+)
+
+var (
+	// ErrSourceTooLarge is returned by HTTPFetcher for sources larger than its MaxBytes
+	ErrSourceTooLarge = errors.New("imageserver: source too large")
+	// ErrSourceForbidden is returned by HTTPFetcher for sources, or redirects, to hosts or
+	// addresses it may not fetch from
+	ErrSourceForbidden = errors.New("imageserver: source not allowed")
+)
+
+// Fetcher loads the encoded source image named in a request URL
+type Fetcher interface {
+	// Fetch returns the source image, or an error matching ErrSourceNotFound if it does not exist
+	Fetch(ctx context.Context, source string) ([]byte, error)
+}
+
+// FetcherFunc adapts a function to a Fetcher
+type FetcherFunc func(ctx context.Context, source string) ([]byte, error)
+
+// Fetch calls f(ctx, source)
+func (f FetcherFunc) Fetch(ctx context.Context, source string) ([]byte, error) {
+	return f(ctx, source)
+}
+
+// Dir is a Fetcher reading sources from files below a local directory. Sources are slash
+// separated paths which cannot escape the directory.
+type Dir string
+
+// Fetch implements Fetcher
+func (d Dir) Fetch(ctx context.Context, source string) ([]byte, error) {
+	name := filepath.Join(string(d), filepath.FromSlash(path.Clean("/"+source)))
+	buf, err := os.ReadFile(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrSourceNotFound
+	}
+	return buf, err
+}
+
+// HTTPFetcher is a Fetcher downloading sources which are http or https URLs. By default it
+// refuses to connect to loopback, private and link-local addresses, so that request URLs
+// cannot reach internal services, and it checks every redirect like the original URL.
+type HTTPFetcher struct {
+	// Client replaces the default client, which connects directly without a proxy and times
+	// out after DefaultFetchTimeout. The address checks and AllowedHosts only apply to
+	// redirects followed by the default client.
+	Client *http.Client
+	// MaxBytes is the largest source downloaded. Defaults to DefaultMaxSourceBytes.
+	MaxBytes int64
+	// AllowedHosts restricts sources to these host names, compared without case. Entries
+	// of the form "*.example.com" match any subdomain of example.com. Defaults to any host.
+	AllowedHosts []string
+	// AllowPrivateNetworks allows the default client to connect to loopback, private and
+	// link-local addresses
+	AllowPrivateNetworks bool
+
+	once   sync.Once
+	client *http.Client
+}
+
+// Fetch implements Fetcher
+func (f *HTTPFetcher) Fetch(ctx context.Context, source string) ([]byte, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return nil, ErrSourceNotFound
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, invalidRequest("invalid source URL")
+	}
+	if !f.allowsHost(req.URL) {
+		return nil, ErrSourceForbidden
+	}
+
+	client := f.Client
+	if client == nil {
+		f.once.Do(f.initClient)
+		client = f.client
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusNotFound || res.StatusCode == http.StatusGone:
+		return nil, ErrSourceNotFound
+	case res.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("imageserver: fetching source: %s", res.Status)
+	}
+
+	maxBytes := f.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxSourceBytes
+	}
+	buf, err := io.ReadAll(io.LimitReader(res.Body, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(buf)) > maxBytes {
+		return nil, ErrSourceTooLarge
+	}
+	return buf, nil
+}
+
+// initClient creates the default client, which checks the address of every connection and
+// the host of every redirect
+func (f *HTTPFetcher) initClient() {
+	dialer := &net.Dialer{Timeout: DefaultFetchTimeout, Control: f.control}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	f.client = &http.Client{
+		Transport: transport,
+		Timeout:   DefaultFetchTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("imageserver: too many redirects")
+			}
+			if !f.allowsHost(req.URL) {
+				return ErrSourceForbidden
+			}
+			return nil
+		},
+	}
+}
+
+// control rejects connections to loopback, private and link-local addresses after the host
+// name has been resolved, unless AllowPrivateNetworks is set
+func (f *HTTPFetcher) control(network, address string, _ syscall.RawConn) error {
+	if f.AllowPrivateNetworks {
+		return nil
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return ErrSourceForbidden
+	}
+	return nil
+}
+
+// allowsHost reports whether AllowedHosts permits the host of u
+func (f *HTTPFetcher) allowsHost(u *url.URL) bool {
+	if len(f.AllowedHosts) == 0 {
+		return true
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range f.AllowedHosts {
+		allowed = strings.ToLower(allowed)
+		if host == allowed || (strings.HasPrefix(allowed, "*.") && strings.HasSuffix(host, allowed[1:])) {
+			return true
+		}
+	}
+	return false
+}
+
+// Fetchers tries each Fetcher in turn until one finds the source
+type Fetchers []Fetcher
+
+// Fetch implements Fetcher
+func (fs Fetchers) Fetch(ctx context.Context, source string) ([]byte, error) {
+	for _, f := range fs {
+		buf, err := f.Fetch(ctx, source)
+		if !errors.Is(err, ErrSourceNotFound) {
+			return buf, err
+		}
+	}
+	return nil, ErrSourceNotFound
+}
@@ -0,0 +1,77 @@
+package imageserver
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/davidbyttow/govips/v2/vips"
+)
+
+// metrics counts the requests served by a Server
+type metrics struct {
+	lock          sync.Mutex
+	requests      map[int]int64
+	durationSum   float64
+	durationCount int64
+}
+
+func newMetrics() *metrics {
+	return &metrics{requests: make(map[int]int64)}
+}
+
+func (m *metrics) observe(code int, duration time.Duration) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.requests[code]++
+	m.durationSum += duration.Seconds()
+	m.durationCount++
+}
+
+// MetricsHandler returns a handler serving the request counts of the server and the libvips
+// operation counts from vips.ReadRuntimeStats in the Prometheus text format. Operation counts
+// are only collected when libvips was started with Config.CollectStats.
+func (s *Server) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		out := bufio.NewWriter(w)
+		defer out.Flush()
+
+		m := s.metrics
+		m.lock.Lock()
+		codes := make([]int, 0, len(m.requests))
+		for code := range m.requests {
+			codes = append(codes, code)
+		}
+		sort.Ints(codes)
+
+		fmt.Fprintln(out, "# HELP govips_server_requests_total Image requests served, by HTTP status code.")
+		fmt.Fprintln(out, "# TYPE govips_server_requests_total counter")
+		for _, code := range codes {
+			fmt.Fprintf(out, "govips_server_requests_total{code=\"%d\"} %d\n", code, m.requests[code])
+		}
+		fmt.Fprintln(out, "# HELP govips_server_request_duration_seconds Time spent serving image requests.")
+		fmt.Fprintln(out, "# TYPE govips_server_request_duration_seconds summary")
+		fmt.Fprintf(out, "govips_server_request_duration_seconds_sum %s\n", strconv.FormatFloat(m.durationSum, 'g', -1, 64))
+		fmt.Fprintf(out, "govips_server_request_duration_seconds_count %d\n", m.durationCount)
+		m.lock.Unlock()
+
+		var stats vips.RuntimeStats
+		vips.ReadRuntimeStats(&stats)
+		operations := make([]string, 0, len(stats.OperationCounts))
+		for operation := range stats.OperationCounts {
+			operations = append(operations, operation)
+		}
+		sort.Strings(operations)
+
+		fmt.Fprintln(out, "# HELP govips_operations_total Operations run by govips.")
+		fmt.Fprintln(out, "# TYPE govips_operations_total counter")
+		for _, operation := range operations {
+			fmt.Fprintf(out, "govips_operations_total{operation=%q} %d\n", operation, stats.OperationCounts[operation])
+		}
+	})
+}
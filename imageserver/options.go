@@ -0,0 +1,233 @@
+package imageserver
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/davidbyttow/govips/v2/vips"
+)
+
+var (
+	// ErrInvalidRequest is wrapped by errors for malformed or disallowed processing options,
+	// which the Server answers with 400 Bad Request
+	ErrInvalidRequest = errors.New("imageserver: invalid request")
+	// ErrSourceNotFound is returned by a Fetcher when the source does not exist, which the
+	// Server answers with 404 Not Found
+	ErrSourceNotFound = errors.New("imageserver: source not found")
+)
+
+func invalidRequest(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidRequest, fmt.Sprintf(format, args...))
+}
+
+// ResizeMode is how an image is fitted to the requested width and height
+type ResizeMode string
+
+// ResizeMode enum
+const (
+	// ResizeFit scales the image to fit within the width and height, keeping the aspect ratio
+	ResizeFit ResizeMode = "fit"
+	// ResizeFill scales the image to cover the width and height and crops the overflow
+	ResizeFill ResizeMode = "fill"
+	// ResizeForce scales the image to exactly the width and height, distorting it if needed
+	ResizeForce ResizeMode = "force"
+	// ResizePad fits the image within the width and height and pads it to exactly that size
+	ResizePad ResizeMode = "pad"
+)
+
+// cropKeywords maps the optional last argument of a resize to the crop strategy used by
+// ResizeFill
+var cropKeywords = map[string]vips.Interesting{
+	"smart":     vips.InterestingAttention,
+	"attention": vips.InterestingAttention,
+	"entropy":   vips.InterestingEntropy,
+	"centre":    vips.InterestingCentre,
+	"center":    vips.InterestingCentre,
+	"low":       vips.InterestingLow,
+	"high":      vips.InterestingHigh,
+}
+
+// ProcessingOptions are the operations requested by the path of a URL, applied in the order
+// of the fields
+type ProcessingOptions struct {
+	// Resize is empty when the image keeps its size. A zero Width or Height is derived from
+	// the aspect ratio.
+	Resize  ResizeMode
+	Width   int
+	Height  int
+	Crop    vips.Interesting
+	Enlarge bool
+
+	Rotate  vips.Angle
+	Blur    float64
+	Sharpen float64
+
+	// Background flattens transparent images and fills the padding of ResizePad
+	Background *vips.Color
+	// Format is the output format. ImageTypeUnknown keeps the source format.
+	Format vips.ImageType
+	// AutoFormat picks the output format from the Accept header of the request
+	AutoFormat bool
+	Quality    int
+	Strip      bool
+}
+
+// ParsePath parses the processing options at the start of an escaped URL path and returns
+// them with the unescaped source that follows, e.g.
+//
+//	/resize/fill/300/200/smart/format:webp/photos%2Fcat.jpg
+//
+// The options are:
+//
+//	resize/{fit|fill|force|pad}/{width}/{height}[/{smart|entropy|centre|low|high}]
+//	enlarge
+//	rotate:{90|180|270}
+//	blur:{sigma}
+//	sharpen:{sigma}
+//	background:{rrggbb}
+//	format:{jpeg|png|webp|avif|gif|tiff|jxl|heif|auto}
+//	quality:{1-100}
+//	strip
+//
+// The source is everything after the last option. It may be escaped as a single segment.
+func ParsePath(path string) (*ProcessingOptions, string, error) {
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	opts := &ProcessingOptions{Crop: vips.InterestingCentre}
+
+	i := 0
+	for ; i < len(segments)-1; i++ {
+		n, err := opts.parseOption(segments[i:])
+		if err != nil {
+			return nil, "", err
+		}
+		if n == 0 {
+			break
+		}
+		i += n - 1
+	}
+
+	source, err := url.PathUnescape(strings.Join(segments[i:], "/"))
+	if err != nil || source == "" {
+		return nil, "", invalidRequest("missing source")
+	}
+	return opts, source, nil
+}
+
+// parseOption parses the option at the start of segments and returns the number of segments
+// it used, or 0 if segments do not start with an option
+func (o *ProcessingOptions) parseOption(segments []string) (int, error) {
+	switch segments[0] {
+	case "resize":
+		return o.parseResize(segments)
+	case "enlarge":
+		o.Enlarge = true
+		return 1, nil
+	case "strip":
+		o.Strip = true
+		return 1, nil
+	}
+
+	key, value, ok := strings.Cut(segments[0], ":")
+	if !ok {
+		return 0, nil
+	}
+
+	var err error
+	switch key {
+	case "rotate":
+		switch value {
+		case "0":
+			o.Rotate = vips.Angle0
+		case "90":
+			o.Rotate = vips.Angle90
+		case "180":
+			o.Rotate = vips.Angle180
+		case "270":
+			o.Rotate = vips.Angle270
+		default:
+			return 0, invalidRequest("rotate must be 0, 90, 180 or 270")
+		}
+	case "blur":
+		o.Blur, err = parseSigma(value)
+	case "sharpen":
+		o.Sharpen, err = parseSigma(value)
+	case "background":
+		o.Background, err = parseColor(value)
+	case "format":
+		if value == "auto" {
+			o.AutoFormat = true
+			break
+		}
+		if o.Format = vips.ParseImageType(value); o.Format == vips.ImageTypeUnknown {
+			err = invalidRequest("unknown format %q", value)
+		}
+	case "quality":
+		o.Quality, err = strconv.Atoi(value)
+		if err != nil || o.Quality < 1 || o.Quality > 100 {
+			err = invalidRequest("quality must be between 1 and 100")
+		}
+	default:
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+func (o *ProcessingOptions) parseResize(segments []string) (int, error) {
+	// The source must follow the resize arguments
+	if len(segments) < 5 {
+		return 0, invalidRequest("resize needs a mode, width and height")
+	}
+
+	switch mode := ResizeMode(segments[1]); mode {
+	case ResizeFit, ResizeFill, ResizeForce, ResizePad:
+		o.Resize = mode
+	default:
+		return 0, invalidRequest("unknown resize mode %q", segments[1])
+	}
+
+	var err error
+	if o.Width, err = strconv.Atoi(segments[2]); err != nil || o.Width < 0 {
+		return 0, invalidRequest("invalid resize width %q", segments[2])
+	}
+	if o.Height, err = strconv.Atoi(segments[3]); err != nil || o.Height < 0 {
+		return 0, invalidRequest("invalid resize height %q", segments[3])
+	}
+	if o.Width == 0 && o.Height == 0 {
+		return 0, invalidRequest("resize needs a width or a height")
+	}
+	if o.Resize == ResizePad && (o.Width == 0 || o.Height == 0) {
+		return 0, invalidRequest("pad needs a width and a height")
+	}
+
+	if crop, ok := cropKeywords[segments[4]]; ok && len(segments) > 5 {
+		o.Crop = crop
+		return 5, nil
+	}
+	return 4, nil
+}
+
+// maxSigma bounds blur and sharpen, whose cost grows with the sigma
+const maxSigma = 100
+
+func parseSigma(s string) (float64, error) {
+	sigma, err := strconv.ParseFloat(s, 64)
+	if err != nil || !(sigma > 0 && sigma <= maxSigma) {
+		return 0, invalidRequest("sigma must be above 0 and at most %d", maxSigma)
+	}
+	return sigma, nil
+}
+
+func parseColor(s string) (*vips.Color, error) {
+	rgb, err := hex.DecodeString(s)
+	if err != nil || len(rgb) != 3 {
+		return nil, invalidRequest("background must be a hex colour such as ffffff")
+	}
+	return &vips.Color{R: rgb[0], G: rgb[1], B: rgb[2]}, nil
+}
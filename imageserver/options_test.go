@@ -0,0 +1,89 @@
+package imageserver
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/davidbyttow/govips/v2/vips"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePath(t *testing.T) {
+	opts, source, err := ParsePath("/resize/fill/300/200/smart/format:webp/quality:80/photos%2Fcat.jpg")
+	require.NoError(t, err)
+	assert.Equal(t, "photos/cat.jpg", source)
+	assert.Equal(t, ResizeFill, opts.Resize)
+	assert.Equal(t, 300, opts.Width)
+	assert.Equal(t, 200, opts.Height)
+	assert.Equal(t, vips.InterestingAttention, opts.Crop)
+	assert.Equal(t, vips.ImageTypeWEBP, opts.Format)
+	assert.Equal(t, 80, opts.Quality)
+}
+
+func TestParsePath_SourceOnly(t *testing.T) {
+	opts, source, err := ParsePath("/photos/cat.jpg")
+	require.NoError(t, err)
+	assert.Equal(t, "photos/cat.jpg", source)
+	assert.Equal(t, &ProcessingOptions{Crop: vips.InterestingCentre}, opts)
+}
+
+func TestParsePath_Options(t *testing.T) {
+	opts, source, err := ParsePath("/resize/pad/100/100/enlarge/rotate:90/blur:1.5/sharpen:2/background:ff8000/format:auto/strip/cat.jpg")
+	require.NoError(t, err)
+	assert.Equal(t, "cat.jpg", source)
+	assert.Equal(t, ResizePad, opts.Resize)
+	assert.Equal(t, vips.InterestingCentre, opts.Crop)
+	assert.True(t, opts.Enlarge)
+	assert.Equal(t, vips.Angle90, opts.Rotate)
+	assert.Equal(t, 1.5, opts.Blur)
+	assert.Equal(t, 2.0, opts.Sharpen)
+	assert.Equal(t, &vips.Color{R: 255, G: 128, B: 0}, opts.Background)
+	assert.True(t, opts.AutoFormat)
+	assert.True(t, opts.Strip)
+}
+
+func TestParsePath_CropKeywordAsSource(t *testing.T) {
+	_, source, err := ParsePath("/resize/fill/300/0/smart")
+	require.NoError(t, err)
+	assert.Equal(t, "smart", source)
+}
+
+func TestParsePath_RemoteSource(t *testing.T) {
+	_, source, err := ParsePath("/resize/fit/300/0/https%3A%2F%2Fexample.com%2Fcat.jpg")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/cat.jpg", source)
+}
+
+func TestParsePath_Invalid(t *testing.T) {
+	for _, path := range []string{
+		"/",
+		"/resize/fit/300/200",
+		"/resize/stretch/300/200/cat.jpg",
+		"/resize/fit/0/0/cat.jpg",
+		"/resize/fit/-1/200/cat.jpg",
+		"/resize/pad/300/0/cat.jpg",
+		"/rotate:45/cat.jpg",
+		"/blur:0/cat.jpg",
+		"/sharpen:NaN/cat.jpg",
+		"/background:white/cat.jpg",
+		"/format:bmp2/cat.jpg",
+		"/quality:101/cat.jpg",
+		"/strip/",
+	} {
+		_, _, err := ParsePath(path)
+		assert.True(t, errors.Is(err, ErrInvalidRequest), path)
+	}
+}
+
+func TestSign(t *testing.T) {
+	key := []byte("secret")
+	path := "/resize/fit/300/200/cat.jpg"
+	signature := Sign(key, path)
+
+	assert.True(t, verify(key, path, signature))
+	assert.False(t, verify(key, "/resize/fit/3000/2000/cat.jpg", signature))
+	assert.False(t, verify([]byte("other"), path, signature))
+	assert.False(t, verify(key, path, ""))
+	assert.Equal(t, path+"?signature="+signature, SignPath(key, path))
+}
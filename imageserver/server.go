@@ -0,0 +1,338 @@
+// Package imageserver is an HTTP server transforming images with govips according to options
+// in the request URL, in the style of imgproxy and thumbor. For example
+//
+//	GET /resize/fill/300/200/smart/format:webp/photos%2Fcat.jpg
+//
+// fetches photos/cat.jpg through the configured Fetcher, crops it to 300x200 around its most
+// interesting part and returns it as WebP. See ParsePath for the available options.
+// vips.Startup must be called before the server handles requests.
+package imageserver
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/davidbyttow/govips/v2/vips"
+)
+
+// Defaults used when the matching Config field is zero
+const (
+	DefaultMaxSourcePixels = 50_000_000
+	DefaultMaxDimension    = 8192
+	DefaultCacheMaxAge     = 24 * time.Hour
+)
+
+// Config configures a Server
+type Config struct {
+	// Fetcher loads the source images. It is required.
+	Fetcher Fetcher
+	// Key enables signed URLs. When set, every request must carry the Sign signature of
+	// its path in the SignatureParam query parameter.
+	Key []byte
+	// MaxSourcePixels is the largest source image accepted, checked from its header before
+	// it is decoded. Defaults to DefaultMaxSourcePixels.
+	MaxSourcePixels int
+	// MaxWidth and MaxHeight bound the requested output size. Default to DefaultMaxDimension.
+	MaxWidth  int
+	MaxHeight int
+	// CacheMaxAge is sent in the Cache-Control header of images. Defaults to DefaultCacheMaxAge.
+	CacheMaxAge time.Duration
+}
+
+// Server is an http.Handler serving images transformed according to the request path
+type Server struct {
+	config  Config
+	metrics *metrics
+}
+
+// New creates a Server
+func New(config Config) *Server {
+	if config.MaxSourcePixels <= 0 {
+		config.MaxSourcePixels = DefaultMaxSourcePixels
+	}
+	if config.MaxWidth <= 0 {
+		config.MaxWidth = DefaultMaxDimension
+	}
+	if config.MaxHeight <= 0 {
+		config.MaxHeight = DefaultMaxDimension
+	}
+	if config.CacheMaxAge <= 0 {
+		config.CacheMaxAge = DefaultCacheMaxAge
+	}
+	return &Server{config: config, metrics: newMetrics()}
+}
+
+// statusWriter remembers the status code of a response for the metrics
+type statusWriter struct {
+	http.ResponseWriter
+	code int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.code = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	sw := &statusWriter{ResponseWriter: w, code: http.StatusOK}
+	s.serve(sw, r)
+	s.metrics.observe(sw.code, time.Since(start))
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := r.URL.EscapedPath()
+	if len(s.config.Key) > 0 && !verify(s.config.Key, path, r.URL.Query().Get(SignatureParam)) {
+		http.Error(w, "invalid signature", http.StatusForbidden)
+		return
+	}
+
+	opts, source, err := ParsePath(path)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if opts.Width > s.config.MaxWidth || opts.Height > s.config.MaxHeight {
+		writeError(w, invalidRequest("size exceeds %dx%d", s.config.MaxWidth, s.config.MaxHeight))
+		return
+	}
+
+	buf, err := s.config.Fetcher.Fetch(r.Context(), source)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	accept := ""
+	if opts.AutoFormat {
+		accept = r.Header.Get("Accept")
+		w.Header().Set("Vary", "Accept")
+	}
+
+	// The response only depends on the path, the source and for format:auto the Accept header
+	hash := sha256.New()
+	hash.Write([]byte(path + "\n" + accept + "\n"))
+	hash.Write(buf)
+	etag := `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(s.config.CacheMaxAge.Seconds())))
+
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	body, format, err := s.process(r.Context(), buf, opts, accept)
+	if err != nil {
+		w.Header().Del("ETag")
+		w.Header().Del("Cache-Control")
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", format.MIMEType())
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(body)
+	}
+}
+
+// process applies opts to the source image and encodes the result
+func (s *Server) process(ctx context.Context, buf []byte, opts *ProcessingOptions, accept string) ([]byte, vips.ImageType, error) {
+	params := vips.NewImportParams()
+	params.AutoRotate.Set(true)
+	params.MaxPixels.Set(s.config.MaxSourcePixels)
+
+	// Loading only reads the header, which checks the pixel limit before any decoding
+	img, err := vips.LoadImageFromBufferContext(ctx, buf, params)
+	if err != nil {
+		return nil, vips.ImageTypeUnknown, err
+	}
+	defer func() { img.Close() }()
+
+	if opts.Resize != "" {
+		// Reload as a thumbnail to shrink on load
+		thumbnail, err := s.thumbnail(buf, opts)
+		if err != nil {
+			return nil, vips.ImageTypeUnknown, err
+		}
+		img.Close()
+		img = thumbnail
+
+		if opts.Resize == ResizePad {
+			background := opts.Background
+			if background == nil {
+				background = &vips.Color{R: 255, G: 255, B: 255}
+			}
+			left, top := (opts.Width-img.Width())/2, (opts.Height-img.Height())/2
+			if err := img.EmbedBackground(left, top, opts.Width, opts.Height, background); err != nil {
+				return nil, vips.ImageTypeUnknown, err
+			}
+		}
+	}
+
+	if opts.Rotate != vips.Angle0 {
+		if err := img.Rotate(opts.Rotate); err != nil {
+			return nil, vips.ImageTypeUnknown, err
+		}
+	}
+	if opts.Blur > 0 {
+		if err := img.GaussianBlur(opts.Blur); err != nil {
+			return nil, vips.ImageTypeUnknown, err
+		}
+	}
+	if opts.Sharpen > 0 {
+		if err := img.Sharpen(opts.Sharpen, 1, 2); err != nil {
+			return nil, vips.ImageTypeUnknown, err
+		}
+	}
+
+	format := outputFormat(img, opts, accept)
+	if img.HasAlpha() && (format == vips.ImageTypeJPEG || opts.Background != nil) {
+		background := opts.Background
+		if background == nil {
+			background = &vips.Color{R: 255, G: 255, B: 255}
+		}
+		if err := img.Flatten(background); err != nil {
+			return nil, vips.ImageTypeUnknown, err
+		}
+	}
+
+	body, err := export(ctx, img, format, opts)
+	return body, format, err
+}
+
+// thumbnail loads the source at the size requested by opts. A width or height derived from
+// the aspect ratio is bounded by MaxWidth or MaxHeight.
+func (s *Server) thumbnail(buf []byte, opts *ProcessingOptions) (*vips.ImageRef, error) {
+	width, height := opts.Width, opts.Height
+	crop, size := vips.InterestingNone, vips.SizeDown
+	if opts.Enlarge {
+		size = vips.SizeBoth
+	}
+
+	switch {
+	case width == 0 || height == 0:
+		// A single dimension always keeps the aspect ratio
+	case opts.Resize == ResizeFill:
+		crop = opts.Crop
+	case opts.Resize == ResizeForce:
+		size = vips.SizeForce
+	}
+	if width == 0 {
+		width = s.config.MaxWidth
+	}
+	if height == 0 {
+		height = s.config.MaxHeight
+	}
+	return vips.LoadThumbnailFromBuffer(buf, width, height, crop, size, nil)
+}
+
+// outputFormat picks the format of the response: the requested one, one accepted by the
+// client for format:auto, or else the source format if it can be saved
+func outputFormat(img *vips.ImageRef, opts *ProcessingOptions, accept string) vips.ImageType {
+	switch {
+	case opts.AutoFormat:
+		format, _ := vips.NegotiateFormat(accept, img, vips.NegotiationPolicy{})
+		return format
+	case opts.Format != vips.ImageTypeUnknown:
+		return opts.Format
+	case vips.IsSaveTypeSupported(img.Format()):
+		return img.Format()
+	case img.HasAlpha():
+		return vips.ImageTypePNG
+	default:
+		return vips.ImageTypeJPEG
+	}
+}
+
+func export(ctx context.Context, img *vips.ImageRef, format vips.ImageType, opts *ProcessingOptions) ([]byte, error) {
+	var buf []byte
+	var err error
+	switch format {
+	case vips.ImageTypePNG:
+		params := vips.NewPngExportParams()
+		params.StripMetadata = opts.Strip
+		buf, _, err = img.ExportPngContext(ctx, params)
+	case vips.ImageTypeWEBP:
+		params := vips.NewWebpExportParams()
+		params.StripMetadata = opts.Strip
+		if opts.Quality > 0 {
+			params.Quality = opts.Quality
+		}
+		buf, _, err = img.ExportWebpContext(ctx, params)
+	case vips.ImageTypeAVIF:
+		params := vips.NewAvifExportParams()
+		params.StripMetadata = opts.Strip
+		if opts.Quality > 0 {
+			params.Quality = opts.Quality
+		}
+		buf, _, err = img.ExportAvifContext(ctx, params)
+	case vips.ImageTypeHEIF:
+		params := vips.NewHeifExportParams()
+		if opts.Quality > 0 {
+			params.Quality = opts.Quality
+		}
+		buf, _, err = img.ExportHeifContext(ctx, params)
+	case vips.ImageTypeGIF:
+		params := vips.NewGifExportParams()
+		params.StripMetadata = opts.Strip
+		buf, _, err = img.ExportGIFContext(ctx, params)
+	case vips.ImageTypeTIFF:
+		params := vips.NewTiffExportParams()
+		params.StripMetadata = opts.Strip
+		if opts.Quality > 0 {
+			params.Quality = opts.Quality
+		}
+		buf, _, err = img.ExportTiffContext(ctx, params)
+	case vips.ImageTypeJXL:
+		params := vips.NewJxlExportParams()
+		if opts.Quality > 0 {
+			params.Quality = opts.Quality
+		}
+		buf, _, err = img.ExportJxlContext(ctx, params)
+	case vips.ImageTypeJPEG:
+		params := vips.NewJpegExportParams()
+		params.StripMetadata = opts.Strip
+		if opts.Quality > 0 {
+			params.Quality = opts.Quality
+		}
+		buf, _, err = img.ExportJpegContext(ctx, params)
+	default:
+		err = invalidRequest("cannot encode %s", format.MIMEType())
+	}
+	return buf, err
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidRequest):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrSourceNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrSourceForbidden):
+		// Keep the resolved address named by the wrapping errors private
+		http.Error(w, ErrSourceForbidden.Error(), http.StatusForbidden)
+	case errors.Is(err, ErrSourceTooLarge), errors.Is(err, vips.ErrImageTooLarge):
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, vips.ErrUnsupportedImageFormat), errors.Is(err, vips.ErrUnsupportedSaveFormat):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+	default:
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,275 @@
+package imageserver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/davidbyttow/govips/v2/vips"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const resources = "../resources/"
+
+func serve(t *testing.T, server http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	require.NoError(t, vips.Startup(nil))
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	return rec
+}
+
+func get(t *testing.T, server http.Handler, target string) *httptest.ResponseRecorder {
+	return serve(t, server, httptest.NewRequest(http.MethodGet, target, nil))
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder) *vips.ImageRef {
+	img, err := vips.NewImageFromBuffer(rec.Body.Bytes())
+	require.NoError(t, err)
+	t.Cleanup(img.Close)
+	return img
+}
+
+func TestServer_Fit(t *testing.T) {
+	server := New(Config{Fetcher: Dir(resources)})
+
+	rec := get(t, server, "/resize/fit/300/300/png-24bit.png")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "image/png", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "public, max-age=86400", rec.Header().Get("Cache-Control"))
+	assert.NotEmpty(t, rec.Header().Get("ETag"))
+
+	img := decode(t, rec)
+	assert.Equal(t, 300, img.Width())
+	assert.Equal(t, 169, img.Height())
+}
+
+func TestServer_FillWebp(t *testing.T) {
+	server := New(Config{Fetcher: Dir(resources)})
+
+	rec := get(t, server, "/resize/fill/300/200/smart/format:webp/png-24bit.png")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "image/webp", rec.Header().Get("Content-Type"))
+
+	img := decode(t, rec)
+	assert.Equal(t, vips.ImageTypeWEBP, img.Format())
+	assert.Equal(t, 300, img.Width())
+	assert.Equal(t, 200, img.Height())
+}
+
+func TestServer_Pad(t *testing.T) {
+	server := New(Config{Fetcher: Dir(resources)})
+
+	rec := get(t, server, "/resize/pad/300/300/background:ff0000/png-24bit.png")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	img := decode(t, rec)
+	assert.Equal(t, 300, img.Width())
+	assert.Equal(t, 300, img.Height())
+	pixel, err := img.GetPoint(0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []float64{255, 0, 0}, pixel[:3])
+}
+
+func TestServer_AutoFormat(t *testing.T) {
+	server := New(Config{Fetcher: Dir(resources)})
+
+	req := httptest.NewRequest(http.MethodGet, "/resize/fit/100/0/format:auto/jpg-24bit.jpg", nil)
+	req.Header.Set("Accept", "image/webp,*/*")
+	rec := serve(t, server, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "image/webp", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "Accept", rec.Header().Get("Vary"))
+	assert.Equal(t, 100, decode(t, rec).Width())
+}
+
+func TestServer_Rotate(t *testing.T) {
+	server := New(Config{Fetcher: Dir(resources)})
+
+	rec := get(t, server, "/resize/fit/300/0/rotate:90/png-24bit.png")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	img := decode(t, rec)
+	assert.Equal(t, 169, img.Width())
+	assert.Equal(t, 300, img.Height())
+}
+
+func TestServer_EnlargeSingleDimension(t *testing.T) {
+	server := New(Config{Fetcher: Dir(resources), MaxWidth: 4000, MaxHeight: 1000})
+
+	// The derived height stays within MaxHeight, narrowing the image to keep its aspect ratio
+	rec := get(t, server, "/resize/fit/3000/0/enlarge/png-24bit.png")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	img := decode(t, rec)
+	assert.InDelta(t, 1778, img.Width(), 1)
+	assert.Equal(t, 1000, img.Height())
+}
+
+func TestServer_NotModified(t *testing.T) {
+	server := New(Config{Fetcher: Dir(resources), CacheMaxAge: time.Minute})
+
+	rec := get(t, server, "/resize/fit/100/100/jpg-24bit.jpg")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "public, max-age=60", rec.Header().Get("Cache-Control"))
+	etag := rec.Header().Get("ETag")
+
+	req := httptest.NewRequest(http.MethodGet, "/resize/fit/100/100/jpg-24bit.jpg", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = serve(t, server, req)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.Bytes())
+
+	rec = get(t, server, "/resize/fit/200/200/jpg-24bit.jpg")
+	assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+}
+
+func TestServer_Signature(t *testing.T) {
+	key := []byte("secret")
+	server := New(Config{Fetcher: Dir(resources), Key: key})
+
+	rec := get(t, server, "/resize/fit/100/100/jpg-24bit.jpg")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = get(t, server, SignPath([]byte("other"), "/resize/fit/100/100/jpg-24bit.jpg"))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = get(t, server, SignPath(key, "/resize/fit/100/100/jpg-24bit.jpg"))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestServer_Errors(t *testing.T) {
+	server := New(Config{Fetcher: Dir(resources), MaxWidth: 1000, MaxHeight: 1000, MaxSourcePixels: 1_000_000})
+
+	assert.Equal(t, http.StatusBadRequest, get(t, server, "/resize/fit/300/jpg-24bit.jpg").Code)
+	assert.Equal(t, http.StatusBadRequest, get(t, server, "/resize/fit/2000/100/jpg-24bit.jpg").Code)
+	assert.Equal(t, http.StatusNotFound, get(t, server, "/resize/fit/100/100/missing.jpg").Code)
+	assert.Equal(t, http.StatusNotFound, get(t, server, "/resize/fit/100/100/..%2F..%2Fetc%2Fpasswd").Code)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, get(t, server, "/resize/fit/100/100/png-24bit.png").Code)
+
+	rec := serve(t, server, httptest.NewRequest(http.MethodPost, "/jpg-24bit.jpg", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestServer_Head(t *testing.T) {
+	server := New(Config{Fetcher: Dir(resources)})
+
+	rec := serve(t, server, httptest.NewRequest(http.MethodHead, "/resize/fit/100/100/jpg-24bit.jpg", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEqual(t, "0", rec.Header().Get("Content-Length"))
+	assert.Empty(t, rec.Body.Bytes())
+}
+
+func TestServer_Metrics(t *testing.T) {
+	server := New(Config{Fetcher: Dir(resources)})
+	get(t, server, "/resize/fit/100/100/jpg-24bit.jpg")
+	get(t, server, "/resize/fit/100/100/missing.jpg")
+
+	rec := get(t, server.MetricsHandler(), "/metrics")
+	require.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	assert.Contains(t, body, `govips_server_requests_total{code="200"} 1`)
+	assert.Contains(t, body, `govips_server_requests_total{code="404"} 1`)
+	assert.Contains(t, body, "govips_server_request_duration_seconds_count 2\n")
+	assert.True(t, strings.Contains(body, "# TYPE govips_operations_total counter"))
+}
+
+func TestFetchers(t *testing.T) {
+	fallback := FetcherFunc(func(ctx context.Context, source string) ([]byte, error) {
+		return []byte(source), nil
+	})
+	fetcher := Fetchers{Dir(resources), &HTTPFetcher{}, fallback}
+
+	buf, err := fetcher.Fetch(context.Background(), "jpg-24bit.jpg")
+	require.NoError(t, err)
+	expected, err := os.ReadFile(resources + "jpg-24bit.jpg")
+	require.NoError(t, err)
+	assert.Equal(t, expected, buf)
+
+	buf, err = fetcher.Fetch(context.Background(), "missing.jpg")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("missing.jpg"), buf)
+
+	_, err = Fetchers{Dir(resources)}.Fetch(context.Background(), "missing.jpg")
+	assert.True(t, errors.Is(err, ErrSourceNotFound))
+}
+
+func TestHTTPFetcher(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/cat.jpg" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("0123456789"))
+	}))
+	defer origin.Close()
+
+	fetcher := &HTTPFetcher{MaxBytes: 10, AllowPrivateNetworks: true}
+	buf, err := fetcher.Fetch(context.Background(), origin.URL+"/cat.jpg")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("0123456789"), buf)
+
+	_, err = fetcher.Fetch(context.Background(), origin.URL+"/dog.jpg")
+	assert.True(t, errors.Is(err, ErrSourceNotFound))
+
+	fetcher.MaxBytes = 5
+	_, err = fetcher.Fetch(context.Background(), origin.URL+"/cat.jpg")
+	assert.True(t, errors.Is(err, ErrSourceTooLarge))
+}
+
+func TestHTTPFetcher_Forbidden(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, strings.Replace(r.URL.Query().Get("to"), "127.0.0.1", "localhost", 1), http.StatusFound)
+			return
+		}
+		_, _ = w.Write([]byte("0123456789"))
+	}))
+	defer origin.Close()
+
+	// Loopback addresses are refused by default
+	_, err := (&HTTPFetcher{}).Fetch(context.Background(), origin.URL+"/cat.jpg")
+	assert.True(t, errors.Is(err, ErrSourceForbidden))
+
+	fetcher := &HTTPFetcher{AllowedHosts: []string{"127.0.0.1"}, AllowPrivateNetworks: true}
+	_, err = fetcher.Fetch(context.Background(), origin.URL+"/cat.jpg")
+	require.NoError(t, err)
+	_, err = fetcher.Fetch(context.Background(), strings.Replace(origin.URL, "127.0.0.1", "localhost", 1)+"/cat.jpg")
+	assert.True(t, errors.Is(err, ErrSourceForbidden))
+
+	// Redirects are checked like the original URL
+	_, err = fetcher.Fetch(context.Background(), origin.URL+"/redirect?to="+origin.URL+"/cat.jpg")
+	assert.True(t, errors.Is(err, ErrSourceForbidden))
+}
+
+func TestHTTPFetcher_Control(t *testing.T) {
+	fetcher := &HTTPFetcher{}
+	for _, address := range []string{"127.0.0.1:80", "[::1]:80", "10.0.0.1:80", "192.168.1.1:443", "169.254.169.254:80", "[fe80::1]:80", "0.0.0.0:80"} {
+		assert.True(t, errors.Is(fetcher.control("tcp", address, nil), ErrSourceForbidden), address)
+	}
+	assert.NoError(t, fetcher.control("tcp", "93.184.216.34:443", nil))
+
+	fetcher.AllowPrivateNetworks = true
+	assert.NoError(t, fetcher.control("tcp", "169.254.169.254:80", nil))
+}
+
+func TestHTTPFetcher_AllowedHosts(t *testing.T) {
+	fetcher := &HTTPFetcher{AllowedHosts: []string{"images.example.com", "*.cdn.example.com"}}
+	for host, allowed := range map[string]bool{
+		"images.example.com":   true,
+		"IMAGES.example.com":   true,
+		"a.cdn.example.com":    true,
+		"cdn.example.com":      false,
+		"evilcdn.example.com":  false,
+		"example.com":          false,
+		"images.example.com.x": false,
+	} {
+		assert.Equal(t, allowed, fetcher.allowsHost(&url.URL{Host: host + ":8080"}), host)
+	}
+}
@@ -0,0 +1,34 @@
+package imageserver
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// SignatureParam is the query parameter holding the signature of a URL
+const SignatureParam = "signature"
+
+// Sign returns the signature of an escaped URL path: the unpadded base64url encoded
+// HMAC-SHA256 of the path with key
+func Sign(key []byte, path string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(path))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// SignPath returns the escaped URL path with its signature appended as a query parameter
+func SignPath(key []byte, path string) string {
+	return path + "?" + SignatureParam + "=" + Sign(key, path)
+}
+
+// verify reports whether signature is the signature of path
+func verify(key []byte, path, signature string) bool {
+	expected, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(path))
+	return hmac.Equal(mac.Sum(nil), expected)
+}
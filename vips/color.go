@@ -0,0 +1,257 @@
+package vips
+
+import "math"
+
+// WhitePoint is the XYZ tristimulus value of a reference white, with Y normalised to 100
+type WhitePoint [3]float64
+
+// Standard illuminants, matching the values used by libvips
+var (
+	WhitePointD65 = WhitePoint{95.0470, 100.0, 108.8827}
+	WhitePointD50 = WhitePoint{96.4250, 100.0, 82.4680}
+	WhitePointD55 = WhitePoint{95.6831, 100.0, 92.1494}
+	WhitePointD75 = WhitePoint{94.9682, 100.0, 122.5710}
+	WhitePointA   = WhitePoint{109.8503, 100.0, 35.5849}
+	WhitePointE   = WhitePoint{100.0, 100.0, 100.0}
+)
+
+// XYZColor is a CIE XYZ colour with Y in the range 0-100, as in libvips
+type XYZColor struct {
+	X, Y, Z float64
+}
+
+// LabColor is a CIELAB colour with L in the range 0-100
+type LabColor struct {
+	L, A, B float64
+}
+
+// LChColor is a CIELAB colour in polar form, with the hue H in degrees
+type LChColor struct {
+	L, C, H float64
+}
+
+// YxyColor is a CIE Yxy colour: luminance Y and the chromaticity coordinates x and y
+type YxyColor struct {
+	Y             float64
+	ChromaticityX float64
+	ChromaticityY float64
+}
+
+// OklabColor is an Oklab colour with L in the range 0-1
+type OklabColor struct {
+	L, A, B float64
+}
+
+// OklchColor is an Oklab colour in polar form, with the hue H in degrees
+type OklchColor struct {
+	L, C, H float64
+}
+
+// CIE constants for the Lab transfer function
+const (
+	labEpsilon = 216.0 / 24389.0
+	labKappa   = 24389.0 / 27.0
+)
+
+// ToXYZ converts an sRGB colour to XYZ relative to D65
+func (c Color) ToXYZ() XYZColor {
+	r, g, b := srgbToLinear(c.R), srgbToLinear(c.G), srgbToLinear(c.B)
+	return XYZColor{
+		X: 100 * (0.4124564*r + 0.3575761*g + 0.1804375*b),
+		Y: 100 * (0.2126729*r + 0.7151522*g + 0.0721750*b),
+		Z: 100 * (0.0193339*r + 0.1191920*g + 0.9503041*b),
+	}
+}
+
+// ToLab converts an sRGB colour to Lab relative to D65
+func (c Color) ToLab() LabColor {
+	return c.ToXYZ().ToLab(WhitePointD65)
+}
+
+// ToOklab converts an sRGB colour to Oklab
+func (c Color) ToOklab() OklabColor {
+	return c.ToXYZ().ToOklab()
+}
+
+// ToXYZ converts the colour part of an sRGBA colour to XYZ relative to D65, ignoring alpha
+func (c ColorRGBA) ToXYZ() XYZColor {
+	return Color{R: c.R, G: c.G, B: c.B}.ToXYZ()
+}
+
+// ToLab converts the colour part of an sRGBA colour to Lab relative to D65, ignoring alpha
+func (c ColorRGBA) ToLab() LabColor {
+	return c.ToXYZ().ToLab(WhitePointD65)
+}
+
+// ToOklab converts the colour part of an sRGBA colour to Oklab, ignoring alpha
+func (c ColorRGBA) ToOklab() OklabColor {
+	return c.ToXYZ().ToOklab()
+}
+
+// ToSRGB converts a D65 XYZ colour to sRGB, clipping colours outside the sRGB gamut
+func (c XYZColor) ToSRGB() Color {
+	x, y, z := c.X/100, c.Y/100, c.Z/100
+	return Color{
+		R: linearToSRGB(3.2404542*x - 1.5371385*y - 0.4985314*z),
+		G: linearToSRGB(-0.9692660*x + 1.8760108*y + 0.0415560*z),
+		B: linearToSRGB(0.0556434*x - 0.2040259*y + 1.0572252*z),
+	}
+}
+
+// ToLab converts the colour to Lab relative to white
+func (c XYZColor) ToLab(white WhitePoint) LabColor {
+	fx := labForward(c.X / white[0])
+	fy := labForward(c.Y / white[1])
+	fz := labForward(c.Z / white[2])
+	return LabColor{
+		L: 116*fy - 16,
+		A: 500 * (fx - fy),
+		B: 200 * (fy - fz),
+	}
+}
+
+// ToYxy converts the colour to Yxy
+func (c XYZColor) ToYxy() YxyColor {
+	sum := c.X + c.Y + c.Z
+	if sum == 0 {
+		return YxyColor{}
+	}
+	return YxyColor{Y: c.Y, ChromaticityX: c.X / sum, ChromaticityY: c.Y / sum}
+}
+
+// ToOklab converts a D65 XYZ colour to Oklab
+func (c XYZColor) ToOklab() OklabColor {
+	x, y, z := c.X/100, c.Y/100, c.Z/100
+	l := math.Cbrt(0.8189330101*x + 0.3618667424*y - 0.1288597137*z)
+	m := math.Cbrt(0.0329845436*x + 0.9293118715*y + 0.0361456387*z)
+	s := math.Cbrt(0.0482003018*x + 0.2643662691*y + 0.6338517070*z)
+	return OklabColor{
+		L: 0.2104542553*l + 0.7936177850*m - 0.0040720468*s,
+		A: 1.9779984951*l - 2.4285922050*m + 0.4505937099*s,
+		B: 0.0259040371*l + 0.7827717662*m - 0.8086757660*s,
+	}
+}
+
+// ToXYZ converts the colour to XYZ relative to white
+func (c LabColor) ToXYZ(white WhitePoint) XYZColor {
+	fy := (c.L + 16) / 116
+	fx := fy + c.A/500
+	fz := fy - c.B/200
+
+	var yr float64
+	if c.L > labKappa*labEpsilon {
+		yr = fy * fy * fy
+	} else {
+		yr = c.L / labKappa
+	}
+
+	return XYZColor{
+		X: white[0] * labInverse(fx),
+		Y: white[1] * yr,
+		Z: white[2] * labInverse(fz),
+	}
+}
+
+// ToLCh converts the colour to polar form
+func (c LabColor) ToLCh() LChColor {
+	chroma, hue := toPolar(c.A, c.B)
+	return LChColor{L: c.L, C: chroma, H: hue}
+}
+
+// ToSRGB converts a D65 Lab colour to sRGB, clipping colours outside the sRGB gamut
+func (c LabColor) ToSRGB() Color {
+	return c.ToXYZ(WhitePointD65).ToSRGB()
+}
+
+// ToLab converts the colour to rectangular form
+func (c LChColor) ToLab() LabColor {
+	a, b := fromPolar(c.C, c.H)
+	return LabColor{L: c.L, A: a, B: b}
+}
+
+// ToXYZ converts the colour to XYZ
+func (c YxyColor) ToXYZ() XYZColor {
+	if c.ChromaticityY == 0 {
+		return XYZColor{}
+	}
+	return XYZColor{
+		X: c.ChromaticityX * c.Y / c.ChromaticityY,
+		Y: c.Y,
+		Z: (1 - c.ChromaticityX - c.ChromaticityY) * c.Y / c.ChromaticityY,
+	}
+}
+
+// ToXYZ converts the colour to XYZ relative to D65
+func (c OklabColor) ToXYZ() XYZColor {
+	l := c.L + 0.3963377774*c.A + 0.2158037573*c.B
+	m := c.L - 0.1055613458*c.A - 0.0638541728*c.B
+	s := c.L - 0.0894841775*c.A - 1.2914855480*c.B
+	l, m, s = l*l*l, m*m*m, s*s*s
+	return XYZColor{
+		X: 100 * (1.2270138511*l - 0.5577999807*m + 0.2812561490*s),
+		Y: 100 * (-0.0405801784*l + 1.1122568696*m - 0.0716766787*s),
+		Z: 100 * (-0.0763812845*l - 0.4214819784*m + 1.5861632204*s),
+	}
+}
+
+// ToOklch converts the colour to polar form
+func (c OklabColor) ToOklch() OklchColor {
+	chroma, hue := toPolar(c.A, c.B)
+	return OklchColor{L: c.L, C: chroma, H: hue}
+}
+
+// ToSRGB converts the colour to sRGB, clipping colours outside the sRGB gamut
+func (c OklabColor) ToSRGB() Color {
+	return c.ToXYZ().ToSRGB()
+}
+
+// ToOklab converts the colour to rectangular form
+func (c OklchColor) ToOklab() OklabColor {
+	a, b := fromPolar(c.C, c.H)
+	return OklabColor{L: c.L, A: a, B: b}
+}
+
+func srgbToLinear(v uint8) float64 {
+	c := float64(v) / 255
+	if c <= 0.04045 {
+		return c / 12.92
+	}
+	return math.Pow((c+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(c float64) uint8 {
+	if c <= 0.0031308 {
+		c *= 12.92
+	} else {
+		c = 1.055*math.Pow(c, 1/2.4) - 0.055
+	}
+	return uint8(math.Round(math.Max(0, math.Min(1, c)) * 255))
+}
+
+func labForward(t float64) float64 {
+	if t > labEpsilon {
+		return math.Cbrt(t)
+	}
+	return (labKappa*t + 16) / 116
+}
+
+func labInverse(f float64) float64 {
+	if f3 := f * f * f; f3 > labEpsilon {
+		return f3
+	}
+	return (116*f - 16) / labKappa
+}
+
+// toPolar returns the chroma and the hue in degrees in the range [0, 360)
+func toPolar(a, b float64) (float64, float64) {
+	hue := math.Atan2(b, a) * 180 / math.Pi
+	if hue < 0 {
+		hue += 360
+	}
+	return math.Hypot(a, b), hue
+}
+
+func fromPolar(chroma, hue float64) (float64, float64) {
+	rad := hue * math.Pi / 180
+	return chroma * math.Cos(rad), chroma * math.Sin(rad)
+}
@@ -0,0 +1,60 @@
+package vips
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestColor_ToLab(t *testing.T) {
+	lab := Color{R: 255}.ToLab()
+	assert.InDelta(t, 53.24, lab.L, 0.01)
+	assert.InDelta(t, 80.09, lab.A, 0.01)
+	assert.InDelta(t, 67.20, lab.B, 0.01)
+
+	lch := lab.ToLCh()
+	assert.InDelta(t, 104.55, lch.C, 0.01)
+	assert.InDelta(t, 40.00, lch.H, 0.01)
+
+	white := Color{R: 255, G: 255, B: 255}.ToLab()
+	assert.InDelta(t, 100, white.L, 0.001)
+	assert.InDelta(t, 0, white.A, 0.001)
+	assert.InDelta(t, 0, white.B, 0.001)
+}
+
+func TestColor_ToOklab(t *testing.T) {
+	oklab := ColorRGBA{R: 255, A: 128}.ToOklab()
+	assert.InDelta(t, 0.6280, oklab.L, 0.0001)
+	assert.InDelta(t, 0.2248, oklab.A, 0.0001)
+	assert.InDelta(t, 0.1258, oklab.B, 0.0001)
+
+	oklch := oklab.ToOklch()
+	assert.InDelta(t, 0.2576, oklch.C, 0.0001)
+	assert.InDelta(t, 29.23, oklch.H, 0.01)
+}
+
+func TestXYZColor_ToYxy(t *testing.T) {
+	yxy := Color{R: 255, G: 255, B: 255}.ToXYZ().ToYxy()
+	assert.InDelta(t, 100, yxy.Y, 0.001)
+	assert.InDelta(t, 0.3127, yxy.ChromaticityX, 0.0001)
+	assert.InDelta(t, 0.3290, yxy.ChromaticityY, 0.0001)
+
+	assert.Equal(t, YxyColor{}, XYZColor{}.ToYxy())
+}
+
+func TestColor_RoundTrip(t *testing.T) {
+	for _, c := range []Color{
+		{R: 12, G: 200, B: 77},
+		{R: 0, G: 0, B: 0},
+		{R: 20, G: 20, B: 20},
+		{R: 255, G: 255, B: 255},
+		{R: 90, G: 30, B: 240},
+	} {
+		assert.Equal(t, c, c.ToLab().ToSRGB())
+		assert.Equal(t, c, c.ToLab().ToLCh().ToLab().ToSRGB())
+		assert.Equal(t, c, c.ToOklab().ToSRGB())
+		assert.Equal(t, c, c.ToOklab().ToOklch().ToOklab().ToSRGB())
+		assert.Equal(t, c, c.ToXYZ().ToYxy().ToXYZ().ToSRGB())
+		assert.Equal(t, c, c.ToXYZ().ToLab(WhitePointD50).ToXYZ(WhitePointD50).ToSRGB())
+	}
+}
@@ -0,0 +1,121 @@
+#include "dzsave.h"
+
+static int set_dzsave_options(VipsOperation *operation, DzSaveParams *params) {
+  if (vips_object_set(VIPS_OBJECT(operation), "in", params->inputImage,
+                      "layout", params->layout, "centre", params->centre,
+                      NULL)) {
+    return 1;
+  }
+
+  if (params->tileSize > 0 &&
+      vips_object_set(VIPS_OBJECT(operation), "tile_size", params->tileSize,
+                      NULL)) {
+    return 1;
+  }
+
+  if (params->overlap >= 0 &&
+      vips_object_set(VIPS_OBJECT(operation), "overlap", params->overlap,
+                      NULL)) {
+    return 1;
+  }
+
+  if (params->depth >= 0 &&
+      vips_object_set(VIPS_OBJECT(operation), "depth", params->depth, NULL)) {
+    return 1;
+  }
+
+  if (params->suffix &&
+      vips_object_set(VIPS_OBJECT(operation), "suffix", params->suffix,
+                      NULL)) {
+    return 1;
+  }
+
+  if (params->id &&
+      vips_object_set(VIPS_OBJECT(operation), "id", params->id, NULL)) {
+    return 1;
+  }
+
+  if (params->skipBlanks &&
+      vips_object_set(VIPS_OBJECT(operation), "skip_blanks",
+                      params->skipBlanksThreshold, NULL)) {
+    return 1;
+  }
+
+  if (params->background && params->backgroundLen > 0) {
+    VipsArrayDouble *background =
+        vips_array_double_new(params->background, params->backgroundLen);
+    int ret = vips_object_set(VIPS_OBJECT(operation), "background",
+                              background, NULL);
+    vips_area_unref(VIPS_AREA(background));
+    if (ret) {
+      return 1;
+    }
+  }
+
+  return 0;
+}
+
+int dzsave_to_file(const char *filename, DzSaveParams *params) {
+  VipsOperation *operation = vips_operation_new("dzsave");
+  if (!operation) {
+    return 1;
+  }
+
+  if (vips_object_set(VIPS_OBJECT(operation), "filename", filename, NULL) ||
+      set_dzsave_options(operation, params)) {
+    g_object_unref(operation);
+    return 1;
+  }
+
+  if (vips_cache_operation_buildp(&operation)) {
+    vips_object_unref_outputs(VIPS_OBJECT(operation));
+    g_object_unref(operation);
+    return 1;
+  }
+
+  vips_object_unref_outputs(VIPS_OBJECT(operation));
+  g_object_unref(operation);
+
+  return 0;
+}
+
+int dzsave_to_zip_buffer(DzSaveParams *params) {
+  VipsBlob *blob;
+  VipsOperation *operation = vips_operation_new("dzsave_buffer");
+  if (!operation) {
+    return 1;
+  }
+
+  if (vips_object_set(VIPS_OBJECT(operation), "container",
+                      VIPS_FOREIGN_DZ_CONTAINER_ZIP, NULL) ||
+      set_dzsave_options(operation, params)) {
+    g_object_unref(operation);
+    return 1;
+  }
+
+  if (params->basename &&
+      vips_object_set(VIPS_OBJECT(operation), "basename", params->basename,
+                      NULL)) {
+    g_object_unref(operation);
+    return 1;
+  }
+
+  if (vips_cache_operation_buildp(&operation)) {
+    vips_object_unref_outputs(VIPS_OBJECT(operation));
+    g_object_unref(operation);
+    return 1;
+  }
+
+  g_object_get(VIPS_OBJECT(operation), "buffer", &blob, NULL);
+  vips_object_unref_outputs(VIPS_OBJECT(operation));
+  g_object_unref(operation);
+
+  VipsArea *area = VIPS_AREA(blob);
+
+  params->outputBuffer = (char *)(area->data);
+  params->outputLen = area->length;
+  area->free_fn = NULL;
+  vips_area_unref(area);
+
+  return 0;
+}
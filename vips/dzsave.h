@@ -0,0 +1,36 @@
+// https://www.libvips.org/API/current/VipsForeignSave.html#vips-dzsave
+
+// clang-format off
+// include order matters
+#include <stdlib.h>
+
+#include <vips/vips.h>
+#include <vips/foreign.h>
+// clang-format on
+
+#ifndef BOOL
+#define BOOL int
+#endif
+
+typedef struct DzSaveParams {
+  VipsImage *inputImage;
+  void *outputBuffer;
+  size_t outputLen;
+
+  const char *basename;
+  VipsForeignDzLayout layout;
+  // Values below zero leave the libvips default for the layout
+  int tileSize;
+  int overlap;
+  int depth;
+  const char *suffix;
+  const char *id;
+  BOOL centre;
+  BOOL skipBlanks;
+  int skipBlanksThreshold;
+  double *background;
+  int backgroundLen;
+} DzSaveParams;
+
+int dzsave_to_file(const char *filename, DzSaveParams *params);
+int dzsave_to_zip_buffer(DzSaveParams *params);
@@ -6,15 +6,163 @@ import "C"
 import (
 	"errors"
 	"fmt"
-	dbg "runtime/debug"
+	"runtime"
+	"strings"
 	"unsafe"
 )
 
 var (
 	// ErrUnsupportedImageFormat when image type is unsupported
 	ErrUnsupportedImageFormat = errors.New("unsupported image format")
+	// ErrImageTooLarge when a loaded image exceeds the limits set in ImportParams
+	ErrImageTooLarge = errors.New("image too large")
+	// ErrTruncatedInput when the input ends before the image could be fully decoded
+	ErrTruncatedInput = errors.New("truncated input")
+	// ErrCorruptInput when the input is damaged or not valid for its format
+	ErrCorruptInput = errors.New("corrupt input")
+	// ErrOutOfMemory when libvips failed to allocate memory
+	ErrOutOfMemory = errors.New("out of memory")
+	// ErrUnsupportedSaveFormat when no saver is available for the requested format
+	ErrUnsupportedSaveFormat = errors.New("unsupported save format")
+	// ErrTargetUnreachable when no quality setting meets the Target passed to ExportWithTarget
+	ErrTargetUnreachable = errors.New("export target unreachable")
 )
 
+// VipsError is an error reported by libvips. Use errors.Is with ErrTruncatedInput, ErrCorruptInput,
+// ErrOutOfMemory or ErrUnsupportedSaveFormat to tell the common failures apart.
+type VipsError struct {
+	// Domain is the libvips domain of the first message, e.g. jpegload_buffer
+	Domain string
+	// Messages are the lines from the libvips error buffer
+	Messages []string
+	// Op is the govips function which called libvips
+	Op string
+
+	kind error
+}
+
+func (e *VipsError) Error() string {
+	msg := strings.Join(e.Messages, "\n")
+	if msg == "" {
+		msg = "unknown libvips error"
+	}
+	if e.Op == "" {
+		return msg
+	}
+	return e.Op + ": " + msg
+}
+
+// Unwrap returns the sentinel error matching the failure, if any
+func (e *VipsError) Unwrap() error {
+	return e.kind
+}
+
+// vipsErrorKinds maps fragments of lowercased libvips messages to sentinel errors, first match wins
+var vipsErrorKinds = []struct {
+	fragment string
+	kind     error
+}{
+	{"out of memory", ErrOutOfMemory},
+	{"unable to allocate", ErrOutOfMemory},
+	{"failed to allocate", ErrOutOfMemory},
+	{"cannot allocate", ErrOutOfMemory},
+	{"premature end", ErrTruncatedInput},
+	{"truncated", ErrTruncatedInput},
+	{"unexpected end", ErrTruncatedInput},
+	{"end of file", ErrTruncatedInput},
+	{"not enough data", ErrTruncatedInput},
+	{"unsupported output type", ErrUnsupportedSaveFormat},
+	{"save_buffer\" not found", ErrUnsupportedSaveFormat},
+	{"save_target\" not found", ErrUnsupportedSaveFormat},
+	{"corrupt", ErrCorruptInput},
+	{"invalid gif header", ErrCorruptInput},
+	{"invalid jpeg file structure", ErrCorruptInput},
+	{"invalid distance too far back", ErrCorruptInput},
+	{"invalid block type", ErrCorruptInput},
+	{"invalid chunk", ErrCorruptInput},
+	{"bogus marker", ErrCorruptInput},
+	{"crc error", ErrCorruptInput},
+	{"incorrect data check", ErrCorruptInput},
+	{"bad adaptive filter", ErrCorruptInput},
+	{"not a tiff", ErrCorruptInput},
+	{"damaged", ErrCorruptInput},
+	{"bad huffman", ErrCorruptInput},
+	{"not a jpeg file", ErrCorruptInput},
+	{"not a png file", ErrCorruptInput},
+	{"not a known", ErrCorruptInput},
+}
+
+func newVipsError(buffer string, op string) *VipsError {
+	e := &VipsError{Op: op}
+
+	for _, line := range strings.Split(buffer, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		e.Messages = append(e.Messages, line)
+		if domain, _, ok := strings.Cut(line, ": "); ok && e.Domain == "" {
+			e.Domain = domain
+		}
+	}
+
+	lower := strings.ToLower(buffer)
+	for _, k := range vipsErrorKinds {
+		if strings.Contains(lower, k.fragment) {
+			e.kind = k.kind
+			break
+		}
+	}
+
+	return e
+}
+
+// callerOp returns the name of the first govips function on the stack outside of the error handlers
+func callerOp() string {
+	pc := make([]uintptr, 8)
+	n := runtime.Callers(3, pc)
+	frames := runtime.CallersFrames(pc[:n])
+	for {
+		frame, more := frames.Next()
+		// github.com/davidbyttow/govips/v2/vips.(*ImageRef).Resize becomes (*ImageRef).Resize
+		name := frame.Function[strings.LastIndex(frame.Function, "/")+1:]
+		name = name[strings.Index(name, ".")+1:]
+		if !strings.HasPrefix(name, "handle") || !strings.HasSuffix(name, "Error") {
+			return name
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// LimitDimension identifies a limit checked when loading an image
+type LimitDimension string
+
+// LimitDimension enum
+const (
+	LimitPixels LimitDimension = "pixels"
+	LimitWidth  LimitDimension = "width"
+	LimitHeight LimitDimension = "height"
+	LimitPages  LimitDimension = "pages"
+)
+
+// ImageTooLargeError reports the limit exceeded by a loaded image. It matches ErrImageTooLarge with errors.Is.
+type ImageTooLargeError struct {
+	Dimension LimitDimension
+	Value     int
+	Limit     int
+}
+
+func (e *ImageTooLargeError) Error() string {
+	return fmt.Sprintf("%v: %s %d exceeds limit of %d", ErrImageTooLarge, e.Dimension, e.Value, e.Limit)
+}
+
+// Is reports whether target is ErrImageTooLarge
+func (e *ImageTooLargeError) Is(target error) bool {
+	return target == ErrImageTooLarge
+}
+
 func handleImageError(out *C.VipsImage) error {
 	if out != nil {
 		clearImage(out)
@@ -35,5 +183,5 @@ func handleVipsError() error {
 	s := C.GoString(C.vips_error_buffer())
 	C.vips_error_clear()
 
-	return fmt.Errorf("%v\nStack:\n%s", s, dbg.Stack())
+	return newVipsError(s, callerOp())
 }
@@ -0,0 +1,73 @@
+package vips
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewVipsError(t *testing.T) {
+	err := newVipsError("VipsJpeg: Premature end of input file\njpegload_buffer: out of order read at line 48\n", "vipsLoadFromBuffer")
+
+	assert.Equal(t, "VipsJpeg", err.Domain)
+	assert.Equal(t, []string{"VipsJpeg: Premature end of input file", "jpegload_buffer: out of order read at line 48"}, err.Messages)
+	assert.Equal(t, "vipsLoadFromBuffer", err.Op)
+	assert.Equal(t, "vipsLoadFromBuffer: VipsJpeg: Premature end of input file\njpegload_buffer: out of order read at line 48", err.Error())
+	assert.True(t, errors.Is(err, ErrTruncatedInput))
+	assert.False(t, errors.Is(err, ErrCorruptInput))
+}
+
+func TestNewVipsError__Kinds(t *testing.T) {
+	tests := []struct {
+		buffer string
+		kind   error
+	}{
+		{"VipsJpeg: Corrupt JPEG data: 12 extraneous bytes\n", ErrCorruptInput},
+		{"gifload_buffer: Invalid GIF header\n", ErrCorruptInput},
+		{"vips_tracked: out of memory --- size == 2GB\n", ErrOutOfMemory},
+		{"VipsOperation: class \"heifsave_buffer\" not found\n", ErrUnsupportedSaveFormat},
+		{"govips: Unsupported output type given: 10\n", ErrUnsupportedSaveFormat},
+		{"linear: vector must have 1 or 3 elements\n", nil},
+		{"VipsJpeg: Invalid JPEG file structure: two SOI markers\n", ErrCorruptInput},
+		{"VipsForeignLoadPng: IDAT: CRC error\n", ErrCorruptInput},
+		{"VipsImage: invalid argument\n", nil},
+		{"extract_band: invalid band\n", nil},
+		{"VipsObject: parameter quality: invalid value\n", nil},
+	}
+
+	for _, tt := range tests {
+		err := newVipsError(tt.buffer, "")
+		if tt.kind == nil {
+			assert.Nil(t, err.Unwrap(), tt.buffer)
+		} else {
+			assert.True(t, errors.Is(err, tt.kind), tt.buffer)
+		}
+	}
+}
+
+func TestVipsError__TruncatedJpeg(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	buf, err := os.ReadFile(resources + "jpg-24bit.jpg")
+	require.NoError(t, err)
+
+	params := NewImportParams()
+	params.FailOnError.Set(true)
+
+	img, err := LoadImageFromBuffer(buf[:len(buf)/2], params)
+	if err == nil {
+		defer img.Close()
+		_, _, err = img.ExportPng(nil)
+	}
+	require.Error(t, err)
+
+	var vipsErr *VipsError
+	require.True(t, errors.As(err, &vipsErr))
+	assert.NotEmpty(t, vipsErr.Messages)
+	assert.NotEmpty(t, vipsErr.Op)
+	assert.NotContains(t, err.Error(), "Stack:")
+	assert.True(t, errors.Is(err, ErrTruncatedInput))
+}
@@ -0,0 +1,38 @@
+#include "eval.h"
+
+static void go_preeval(VipsImage *image, VipsProgress *progress,
+                       gpointer user_data) {
+  goEval((uintptr_t)user_data, image, progress, EVAL_PRE);
+}
+
+static void go_eval(VipsImage *image, VipsProgress *progress,
+                    gpointer user_data) {
+  goEval((uintptr_t)user_data, image, progress, EVAL_RUN);
+}
+
+static void go_posteval(VipsImage *image, VipsProgress *progress,
+                        gpointer user_data) {
+  goEval((uintptr_t)user_data, image, progress, EVAL_POST);
+}
+
+// Called when the signal handlers are disconnected so the Go side can drop its
+// reference to the monitor.
+static void go_eval_release(gpointer user_data, GClosure *closure) {
+  goEvalRelease((uintptr_t)user_data);
+}
+
+// Evaluations of images derived from image report their progress on image
+// once progress is enabled, so one set of handlers covers the whole pipeline.
+void connect_eval(VipsImage *image, uintptr_t handle) {
+  vips_image_set_progress(image, TRUE);
+
+  g_signal_connect(image, "preeval", G_CALLBACK(go_preeval), (gpointer)handle);
+  g_signal_connect_data(image, "eval", G_CALLBACK(go_eval), (gpointer)handle,
+                        go_eval_release, 0);
+  g_signal_connect(image, "posteval", G_CALLBACK(go_posteval),
+                   (gpointer)handle);
+}
+
+void disconnect_eval(VipsImage *image, uintptr_t handle) {
+  g_signal_handlers_disconnect_by_data(image, (gpointer)handle);
+}
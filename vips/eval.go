@@ -0,0 +1,81 @@
+package vips
+
+// #include "eval.h"
+import "C"
+
+import (
+	"context"
+	"runtime/cgo"
+	"time"
+)
+
+// evalMonitor watches the evaluation of an image on behalf of a single call.
+type evalMonitor struct {
+	ctx      context.Context
+	progress ProgressFunc
+}
+
+// goEval is called by libvips before, during and after pixels are computed for a monitored image.
+// Setting the kill flag makes libvips abort the evaluation with an error.
+//
+//export goEval
+func goEval(handle C.uintptr_t, image *C.VipsImage, progress *C.VipsProgress, event C.int) {
+	m := cgo.Handle(handle).Value().(*evalMonitor)
+
+	if event == C.EVAL_RUN && m.ctx.Err() != nil {
+		C.vips_image_set_kill(image, C.TRUE)
+		return
+	}
+
+	if m.progress == nil {
+		return
+	}
+
+	switch event {
+	case C.EVAL_PRE:
+		m.progress(0, 0)
+	case C.EVAL_RUN:
+		m.progress(int(progress.percent), time.Duration(progress.eta)*time.Second)
+	case C.EVAL_POST:
+		m.progress(100, 0)
+	}
+}
+
+// goEvalRelease is called once the eval handlers of a monitored image are disconnected.
+//
+//export goEvalRelease
+func goEvalRelease(handle C.uintptr_t) {
+	cgo.Handle(handle).Delete()
+}
+
+// vipsEvaluate runs fn, which must evaluate in, reporting to progress and killing the evaluation
+// once ctx is done. If the evaluation failed because of ctx, ctx.Err() is returned instead of the
+// libvips error.
+func vipsEvaluate(ctx context.Context, in *C.VipsImage, progress ProgressFunc, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Skip the signal handlers when there is nothing to report and nothing can cancel
+	if ctx.Done() == nil && progress == nil {
+		return fn()
+	}
+
+	handle := cgo.NewHandle(&evalMonitor{ctx: ctx, progress: progress})
+	C.connect_eval(in, C.uintptr_t(handle))
+	err := fn()
+	C.disconnect_eval(in, C.uintptr_t(handle))
+	// libvips only clears the kill flag when a region sees it, which it may not have done if
+	// ctx was done near the end of the evaluation
+	C.vips_image_set_kill(in, C.FALSE)
+
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// evaluate runs fn, which must evaluate the image, reporting to the progress function of r.
+func (r *ImageRef) evaluate(fn func() error) error {
+	return vipsEvaluate(context.Background(), r.image, r.progress, fn)
+}
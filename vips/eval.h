@@ -0,0 +1,22 @@
+// https://www.libvips.org/API/current/VipsImage.html#VipsImage-eval
+
+// clang-format off
+// include order matters
+#include <stdint.h>
+#include <stdlib.h>
+#include <glib.h>
+#include <vips/vips.h>
+// clang-format on
+
+typedef enum EvalEvent {
+  EVAL_PRE,
+  EVAL_RUN,
+  EVAL_POST,
+} EvalEvent;
+
+extern void goEval(uintptr_t handle, VipsImage *image, VipsProgress *progress,
+                   int event);
+extern void goEvalRelease(uintptr_t handle);
+
+void connect_eval(VipsImage *image, uintptr_t handle);
+void disconnect_eval(VipsImage *image, uintptr_t handle);
@@ -0,0 +1,196 @@
+package vips
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportJpegContext__Canceled(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	img, err := NewImageFromFile(resources + "jpg-24bit.jpg")
+	require.NoError(t, err)
+	defer img.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	buf, metadata, err := img.ExportJpegContext(ctx, nil)
+	assert.Nil(t, buf)
+	assert.Nil(t, metadata)
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestExportJpegContext__DeadlineDuringEval(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	img, err := NewImageFromFile(resources + "jpg-24bit.jpg")
+	require.NoError(t, err)
+	defer img.Close()
+
+	// Make the pipeline expensive enough to still be running when the deadline passes
+	require.NoError(t, img.Resize(8, KernelLanczos3))
+	require.NoError(t, img.GaussianBlur(20))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	_, _, err = img.ExportJpegContext(ctx, nil)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestExportJpegContext__CanceledDuringEval(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	img, err := NewImageFromFile(resources + "jpg-24bit.jpg")
+	require.NoError(t, err)
+	defer img.Close()
+
+	require.NoError(t, img.Resize(20, KernelLanczos3))
+
+	// Cancel once the evaluation has started, so that the kill flag is set mid export
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	img.SetProgressFunc(func(percent int, eta time.Duration) {
+		cancel()
+	})
+
+	_, _, err = img.ExportJpegContext(ctx, nil)
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	// The image is not left killed
+	img.SetProgressFunc(nil)
+	buf, _, err := img.ExportJpeg(nil)
+	require.NoError(t, err)
+	assert.NotEmpty(t, buf)
+}
+
+func TestExportJpegToContext__Canceled(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	img, err := NewImageFromFile(resources + "jpg-24bit.jpg")
+	require.NoError(t, err)
+	defer img.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var out bytes.Buffer
+	metadata, err := img.ExportJpegToContext(ctx, &out, nil)
+	assert.Nil(t, metadata)
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Zero(t, out.Len())
+}
+
+func TestExportWebpToContext__DeadlineDuringEval(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	img, err := NewImageFromFile(resources + "jpg-24bit.jpg")
+	require.NoError(t, err)
+	defer img.Close()
+
+	require.NoError(t, img.Resize(8, KernelLanczos3))
+	require.NoError(t, img.GaussianBlur(20))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	_, err = img.ExportWebpToContext(ctx, io.Discard, nil)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestSaveToFileContext(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	img, err := NewImageFromFile(resources + "jpg-24bit.jpg")
+	require.NoError(t, err)
+	defer img.Close()
+
+	path := filepath.Join(t.TempDir(), "out.png")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = img.SaveToFileContext(ctx, path, nil)
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	metadata, err := img.SaveToFileContext(context.Background(), path, nil)
+	require.NoError(t, err)
+	assert.Equal(t, ImageTypePNG, metadata.Format)
+}
+
+func TestExportJpegContext__Completes(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	img, err := NewImageFromFile(resources + "jpg-24bit.jpg")
+	require.NoError(t, err)
+	defer img.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	buf, metadata, err := img.ExportJpegContext(ctx, nil)
+	require.NoError(t, err)
+	assert.NotEmpty(t, buf)
+	assert.Equal(t, ImageTypeJPEG, metadata.Format)
+
+	// The signal handler must be gone, later exports are unaffected by ctx
+	cancel()
+	_, _, err = img.ExportJpeg(nil)
+	require.NoError(t, err)
+}
+
+func TestLoadImageFromBufferContext__Canceled(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	img, err := LoadImageFromBufferContext(ctx, []byte{0xff, 0xd8, 0xff}, nil)
+	assert.Nil(t, img)
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestImageRef_SetProgressFunc(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	img, err := NewImageFromFile(resources + "jpg-24bit.jpg")
+	require.NoError(t, err)
+	defer img.Close()
+
+	require.NoError(t, img.Resize(4, KernelLanczos3))
+
+	var percents []int
+	img.SetProgressFunc(func(percent int, eta time.Duration) {
+		percents = append(percents, percent)
+		assert.True(t, eta >= 0)
+	})
+
+	_, _, err = img.ExportPng(nil)
+	require.NoError(t, err)
+
+	require.NotEmpty(t, percents)
+	assert.Equal(t, 0, percents[0])
+	assert.Equal(t, 100, percents[len(percents)-1])
+	for i := 1; i < len(percents); i++ {
+		assert.True(t, percents[i] >= percents[i-1])
+	}
+
+	percents = nil
+	_, err = img.ToBytes()
+	require.NoError(t, err)
+	assert.NotEmpty(t, percents)
+
+	img.SetProgressFunc(nil)
+	percents = nil
+	_, _, err = img.ExportPng(nil)
+	require.NoError(t, err)
+	assert.Empty(t, percents)
+}
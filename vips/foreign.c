@@ -211,6 +211,38 @@ int load_buffer(const char *operationName, void *buf, size_t len,
   return 0;
 }
 
+int load_source(const char *operationName, VipsSource *source,
+                LoadParams *params, SetLoadOptionsFn setLoadOptions) {
+  VipsOperation *operation = vips_operation_new(operationName);
+  if (!operation) {
+    return 1;
+  }
+
+  if (vips_object_set(VIPS_OBJECT(operation), "source", source, NULL)) {
+    g_object_unref(operation);
+    return 1;
+  }
+
+  if (setLoadOptions(operation, params)) {
+    vips_object_unref_outputs(VIPS_OBJECT(operation));
+    g_object_unref(operation);
+    return 1;
+  }
+
+  if (vips_cache_operation_buildp(&operation)) {
+    vips_object_unref_outputs(VIPS_OBJECT(operation));
+    g_object_unref(operation);
+    return 1;
+  }
+
+  g_object_get(VIPS_OBJECT(operation), "out", &params->outputImage, NULL);
+
+  vips_object_unref_outputs(VIPS_OBJECT(operation));
+  g_object_unref(operation);
+
+  return 0;
+}
+
 typedef int (*SetSaveOptionsFn)(VipsOperation *operation, SaveParams *params);
 
 int save_buffer(const char *operationName, SaveParams *params,
@@ -249,6 +281,36 @@ int save_buffer(const char *operationName, SaveParams *params,
   return 0;
 }
 
+int save_target(const char *operationName, SaveParams *params,
+                SetSaveOptionsFn setSaveOptions) {
+  VipsOperation *operation = vips_operation_new(operationName);
+  if (!operation) {
+    return 1;
+  }
+
+  if (vips_object_set(VIPS_OBJECT(operation), "in", params->inputImage,
+                      "target", params->outputTarget, NULL)) {
+    g_object_unref(operation);
+    return 1;
+  }
+
+  if (setSaveOptions(operation, params)) {
+    g_object_unref(operation);
+    return 1;
+  }
+
+  if (vips_cache_operation_buildp(&operation)) {
+    vips_object_unref_outputs(VIPS_OBJECT(operation));
+    g_object_unref(operation);
+    return 1;
+  }
+
+  vips_object_unref_outputs(VIPS_OBJECT(operation));
+  g_object_unref(operation);
+
+  return 0;
+}
+
 // https://libvips.github.io/libvips/API/current/VipsForeignSave.html#vips-jpegsave-buffer
 int set_jpegsave_options(VipsOperation *operation, SaveParams *params) {
   int ret = vips_object_set(
@@ -474,11 +536,75 @@ int load_from_buffer(LoadParams *params, void *buf, size_t len) {
       return load_buffer("jxlload_buffer", buf, len, params,
                           set_jxlload_options);
     default:
-      g_warning("Unsupported input type given: %d", params->inputFormat);
+      vips_error("govips", "Unsupported input type given: %d",
+                 params->inputFormat);
+  }
+  return 1;
+}
+
+int load_from_source(LoadParams *params, VipsSource *source) {
+  switch (params->inputFormat) {
+    case JPEG:
+      return load_source("jpegload_source", source, params,
+                         set_jpegload_options);
+    case PNG:
+      return load_source("pngload_source", source, params,
+                         set_pngload_options);
+    case WEBP:
+      return load_source("webpload_source", source, params,
+                         set_webpload_options);
+    case HEIF:
+      return load_source("heifload_source", source, params,
+                         set_heifload_options);
+    case TIFF:
+      return load_source("tiffload_source", source, params,
+                         set_tiffload_options);
+    case SVG:
+      return load_source("svgload_source", source, params,
+                         set_svgload_options);
+    case GIF:
+      return load_source("gifload_source", source, params,
+                         set_gifload_options);
+    case PDF:
+      return load_source("pdfload_source", source, params,
+                         set_pdfload_options);
+    case MAGICK:
+      return load_source("magickload_source", source, params,
+                         set_magickload_options);
+    case AVIF:
+      return load_source("heifload_source", source, params,
+                         set_heifload_options);
+    case JP2K:
+      return load_source("jp2kload_source", source, params,
+                         set_jp2kload_options);
+    case JXL:
+      return load_source("jxlload_source", source, params,
+                         set_jxlload_options);
+    default:
+      vips_error("govips", "Unsupported input type given: %d",
+                 params->inputFormat);
   }
   return 1;
 }
 
+// Loads an image straight from disk. Load options are passed in the filename
+// option string, e.g. "scan.tif[page=1,access=sequential]".
+int load_from_file(const char *filename, VipsImage **out) {
+  *out = vips_image_new_from_file(filename, NULL);
+  return *out ? 0 : 1;
+}
+
+// Returns the nickname of the loader libvips would pick for the file, e.g.
+// "jpegload", or NULL if the format is not recognised.
+const char *find_file_loader(const char *filename) {
+  const char *name = vips_foreign_find_load(filename);
+  if (!name) {
+    return NULL;
+  }
+
+  return vips_nickname_find(g_type_from_name(name));
+}
+
 int save_to_buffer(SaveParams *params) {
   switch (params->outputFormat) {
     case JPEG:
@@ -511,7 +637,40 @@ int save_to_buffer(SaveParams *params) {
     case MAGICK:
           return save_buffer("magicksave_buffer", params, set_magicksave_options);
     default:
-      g_warning("Unsupported output type given: %d", params->outputFormat);
+      vips_error("govips", "Unsupported output type given: %d",
+                 params->outputFormat);
+  }
+  return 1;
+}
+
+int save_to_target(SaveParams *params) {
+  switch (params->outputFormat) {
+    case JPEG:
+      return save_target("jpegsave_target", params, set_jpegsave_options);
+    case PNG:
+      return save_target("pngsave_target", params, set_pngsave_options);
+    case WEBP:
+      return save_target("webpsave_target", params, set_webpsave_options);
+    case HEIF:
+      return save_target("heifsave_target", params, set_heifsave_options);
+    case TIFF:
+      return save_target("tiffsave_target", params, set_tiffsave_options);
+    case GIF:
+#if (VIPS_MAJOR_VERSION >= 8) && (VIPS_MINOR_VERSION >= 12)
+      return save_target("gifsave_target", params, set_gifsave_options);
+#else
+      vips_error("govips", "GIF export to a target requires libvips 8.12+");
+      return 1;
+#endif
+    case AVIF:
+      return save_target("heifsave_target", params, set_avifsave_options);
+    case JP2K:
+      return save_target("jp2ksave_target", params, set_jp2ksave_options);
+    case JXL:
+      return save_target("jxlsave_target", params, set_jxlsave_options);
+    default:
+      vips_error("govips", "Unsupported output type for target: %d",
+                 params->outputFormat);
   }
   return 1;
 }
@@ -541,6 +700,7 @@ LoadParams create_load_params(ImageType inputFormat) {
 static SaveParams defaultSaveParams = {
     .inputImage = NULL,
     .outputBuffer = NULL,
+    .outputTarget = NULL,
     .outputFormat = JPEG,
     .outputLen = 0,
 
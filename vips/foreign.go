@@ -7,8 +7,12 @@ import (
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"io"
 	"math"
+	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"unsafe"
 
 	"golang.org/x/net/html/charset"
@@ -70,6 +74,60 @@ var imageTypeExtensionMap = map[ImageType]string{
 	ImageTypePSD:    ".psd",
 }
 
+var imageTypeMIMETypeMap = map[ImageType]string{
+	ImageTypeGIF:  "image/gif",
+	ImageTypeJPEG: "image/jpeg",
+	ImageTypePDF:  "application/pdf",
+	ImageTypePNG:  "image/png",
+	ImageTypeSVG:  "image/svg+xml",
+	ImageTypeTIFF: "image/tiff",
+	ImageTypeWEBP: "image/webp",
+	ImageTypeHEIF: "image/heif",
+	ImageTypeBMP:  "image/bmp",
+	ImageTypeAVIF: "image/avif",
+	ImageTypeJP2K: "image/jp2",
+	ImageTypeJXL:  "image/jxl",
+	ImageTypePSD:  "image/vnd.adobe.photoshop",
+}
+
+// mimeTypeAliases are media types in common use besides the canonical ones above
+var mimeTypeAliases = map[string]ImageType{
+	"image/jpg":           ImageTypeJPEG,
+	"image/pjpeg":         ImageTypeJPEG,
+	"image/heic":          ImageTypeHEIF,
+	"image/heif-sequence": ImageTypeHEIF,
+	"image/x-ms-bmp":      ImageTypeBMP,
+	"image/jpx":           ImageTypeJP2K,
+	"image/x-png":         ImageTypePNG,
+	"image/x-tiff":        ImageTypeTIFF,
+}
+
+// saveFileExtMap maps the file extensions understood by SaveToFile to an output ImageType
+var saveFileExtMap = map[string]ImageType{
+	".gif":  ImageTypeGIF,
+	".jpg":  ImageTypeJPEG,
+	".jpeg": ImageTypeJPEG,
+	".jpe":  ImageTypeJPEG,
+	".png":  ImageTypePNG,
+	".tif":  ImageTypeTIFF,
+	".tiff": ImageTypeTIFF,
+	".webp": ImageTypeWEBP,
+	".heic": ImageTypeHEIF,
+	".heif": ImageTypeHEIF,
+	".avif": ImageTypeAVIF,
+	".jp2":  ImageTypeJP2K,
+	".j2k":  ImageTypeJP2K,
+	".jxl":  ImageTypeJXL,
+}
+
+// saveTypeFromFileExt returns the output ImageType for the extension of path, or ImageTypeUnknown
+func saveTypeFromFileExt(path string) ImageType {
+	if imageType, ok := saveFileExtMap[strings.ToLower(filepath.Ext(path))]; ok {
+		return imageType
+	}
+	return ImageTypeUnknown
+}
+
 // ImageTypes defines the various image types supported by govips
 var ImageTypes = map[ImageType]string{
 	ImageTypeGIF:    "gif",
@@ -144,7 +202,46 @@ func (i ImageType) FileExt() string {
 	return ""
 }
 
-// IsTypeSupported checks whether given image type is supported by govips
+// MIMEType returns the media type for the ImageType, or an empty string if it has none
+func (i ImageType) MIMEType() string {
+	return imageTypeMIMETypeMap[i]
+}
+
+// ParseImageType returns the ImageType for a media type such as "image/webp", or a file path
+// or extension such as ".jpg" or "png". It returns ImageTypeUnknown if s is not recognised.
+func ParseImageType(s string) ImageType {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if i := strings.IndexByte(s, ';'); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+
+	if strings.Contains(s, "/") {
+		for imageType, mimeType := range imageTypeMIMETypeMap {
+			if mimeType == s {
+				return imageType
+			}
+		}
+		if imageType, ok := mimeTypeAliases[s]; ok {
+			return imageType
+		}
+	}
+
+	ext := filepath.Ext(s)
+	if ext == "" {
+		ext = "." + s
+	}
+	if imageType, ok := saveFileExtMap[ext]; ok {
+		return imageType
+	}
+	for imageType, typeExt := range imageTypeExtensionMap {
+		if typeExt == ext && imageType != ImageTypeMagick {
+			return imageType
+		}
+	}
+	return ImageTypeUnknown
+}
+
+// IsTypeSupported checks whether given image type can be loaded by govips
 func IsTypeSupported(imageType ImageType) bool {
 	if err := startupIfNeeded(); err != nil {
 		govipsLog("govips", LogLevelError, fmt.Sprintf("failed to start vips: %v", err))
@@ -159,6 +256,17 @@ func IsTypeSupported(imageType ImageType) bool {
 	return supportedImageTypes[imageType]
 }
 
+// IsSaveTypeSupported checks whether images can be exported in the given image type. A format
+// may be loadable but not savable, for example when libvips is built without an encoder.
+func IsSaveTypeSupported(imageType ImageType) bool {
+	if err := startupIfNeeded(); err != nil {
+		govipsLog("govips", LogLevelError, fmt.Sprintf("failed to start vips: %v", err))
+		return false
+	}
+
+	return supportedSaveTypes[imageType]
+}
+
 // DetermineImageType attempts to determine the image type of the given buffer
 func DetermineImageType(buf []byte) ImageType {
 	if len(buf) < 12 {
@@ -347,15 +455,137 @@ func vipsLoadFromBuffer(buf []byte, params *ImportParams) (*C.VipsImage, ImageTy
 		return nil, currentType, originalType, ErrUnsupportedImageFormat
 	}
 
+	if !isLoaderAllowed(originalType) {
+		govipsLog("govips", LogLevelInfo, fmt.Sprintf("loading image type %s is not allowed", ImageTypes[originalType]))
+		return nil, currentType, originalType, ErrUnsupportedImageFormat
+	}
+
 	importParams := createImportParams(currentType, params)
 
 	if err := C.load_from_buffer(&importParams, unsafe.Pointer(&src[0]), C.size_t(len(src))); err != 0 {
 		return nil, currentType, originalType, handleImageError(importParams.outputImage)
 	}
 
+	if err := checkImageLimits(importParams.outputImage, params); err != nil {
+		clearImage(importParams.outputImage)
+		return nil, currentType, originalType, err
+	}
+
 	return importParams.outputImage, currentType, originalType, nil
 }
 
+func vipsLoadFromFile(file string, params *ImportParams) (*C.VipsImage, ImageType, ImageType, error) {
+	incOpCounter("load_file")
+
+	originalType, err := vipsDetermineFileType(file)
+	if err != nil {
+		return nil, ImageTypeUnknown, ImageTypeUnknown, err
+	}
+	currentType := originalType
+
+	// Map image types which are not supported by libvips itself to ImageMagick
+	if isNeedToChangeLoaderToMagick(originalType) {
+		currentType = ImageTypeMagick
+	}
+
+	if !IsTypeSupported(currentType) {
+		govipsLog("govips", LogLevelInfo, fmt.Sprintf("failed to understand image format file=%s", file))
+		return nil, currentType, originalType, ErrUnsupportedImageFormat
+	}
+
+	if !isLoaderAllowed(originalType) {
+		govipsLog("govips", LogLevelInfo, fmt.Sprintf("loading image type %s is not allowed", ImageTypes[originalType]))
+		return nil, currentType, originalType, ErrUnsupportedImageFormat
+	}
+
+	filenameOption := file
+	if options := params.loadOptionString(currentType); options != "" {
+		filenameOption += "[" + options + "]"
+	}
+
+	cFileName := C.CString(filenameOption)
+	defer freeCString(cFileName)
+
+	var out *C.VipsImage
+	if err := C.load_from_file(cFileName, &out); err != 0 {
+		return nil, currentType, originalType, handleImageError(out)
+	}
+
+	if err := checkImageLimits(out, params); err != nil {
+		clearImage(out)
+		return nil, currentType, originalType, err
+	}
+
+	return out, currentType, originalType, nil
+}
+
+// vipsDetermineFileType sniffs the header of a file the same way buffers are sniffed,
+// falling back to asking libvips which loader it would pick.
+func vipsDetermineFileType(file string) (ImageType, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return ImageTypeUnknown, err
+	}
+	defer f.Close()
+
+	header := make([]byte, sniffLength)
+	n, err := io.ReadFull(f, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return ImageTypeUnknown, err
+	}
+
+	if imageType := DetermineImageType(header[:n]); imageType != ImageTypeUnknown {
+		return imageType, nil
+	}
+
+	cFileName := C.CString(file)
+	defer freeCString(cFileName)
+
+	loader := C.find_file_loader(cFileName)
+	if loader == nil {
+		C.vips_error_clear()
+		return ImageTypeUnknown, nil
+	}
+	return imageTypeFromLoader(C.GoString(loader)), nil
+}
+
+// hasImageLimits reports whether params sets any of the limits verified by checkImageLimits
+func hasImageLimits(params *ImportParams) bool {
+	return params != nil &&
+		(params.MaxWidth.IsSet() || params.MaxHeight.IsSet() || params.MaxPages.IsSet() || params.MaxPixels.IsSet())
+}
+
+// checkImageLimits verifies a freshly loaded image, whose pixels are not decoded yet, against the limits in params
+func checkImageLimits(in *C.VipsImage, params *ImportParams) error {
+	width := int(in.Xsize)
+	height := int(in.Ysize)
+	pageHeight := vipsGetPageHeight(in)
+	pages := 1
+	if pageHeight > 0 && height%pageHeight == 0 {
+		pages = height / pageHeight
+	} else {
+		pageHeight = height
+	}
+
+	checks := []struct {
+		dimension LimitDimension
+		value     int
+		limit     IntParameter
+	}{
+		{LimitWidth, width, params.MaxWidth},
+		{LimitHeight, pageHeight, params.MaxHeight},
+		{LimitPages, pages, params.MaxPages},
+		{LimitPixels, width * height, params.MaxPixels},
+	}
+
+	for _, c := range checks {
+		if c.limit.IsSet() && c.value > c.limit.Get() {
+			return &ImageTooLargeError{Dimension: c.dimension, Value: c.value, Limit: c.limit.Get()}
+		}
+	}
+	return nil
+}
+
 func maybeSetBoolParam(p BoolParameter, cp *C.Param) {
 	if p.IsSet() {
 		C.set_bool_param(cp, toGboolean(p.Get()))
@@ -393,9 +623,7 @@ func createImportParams(format ImageType, params *ImportParams) C.LoadParams {
 	return p
 }
 
-func vipsSaveJPEGToBuffer(in *C.VipsImage, params JpegExportParams) ([]byte, error) {
-	incOpCounter("save_jpeg_buffer")
-
+func createJpegSaveParams(in *C.VipsImage, params JpegExportParams) C.struct_SaveParams {
 	p := C.create_save_params(C.JPEG)
 	p.inputImage = in
 	p.stripMetadata = C.int(boolToInt(params.StripMetadata))
@@ -408,12 +636,15 @@ func vipsSaveJPEGToBuffer(in *C.VipsImage, params JpegExportParams) ([]byte, err
 	p.jpegOptimizeScans = C.int(boolToInt(params.OptimizeScans))
 	p.jpegQuantTable = C.int(params.QuantTable)
 
-	return vipsSaveToBuffer(p)
+	return p
 }
 
-func vipsSavePNGToBuffer(in *C.VipsImage, params PngExportParams) ([]byte, error) {
-	incOpCounter("save_png_buffer")
+func vipsSaveJPEGToBuffer(in *C.VipsImage, params JpegExportParams) ([]byte, error) {
+	incOpCounter("save_jpeg_buffer")
+	return vipsSaveToBuffer(createJpegSaveParams(in, params))
+}
 
+func createPngSaveParams(in *C.VipsImage, params PngExportParams) C.struct_SaveParams {
 	p := C.create_save_params(C.PNG)
 	p.inputImage = in
 	p.quality = C.int(params.Quality)
@@ -425,12 +656,15 @@ func vipsSavePNGToBuffer(in *C.VipsImage, params PngExportParams) ([]byte, error
 	p.pngDither = C.double(params.Dither)
 	p.pngBitdepth = C.int(params.Bitdepth)
 
-	return vipsSaveToBuffer(p)
+	return p
 }
 
-func vipsSaveWebPToBuffer(in *C.VipsImage, params WebpExportParams) ([]byte, error) {
-	incOpCounter("save_webp_buffer")
+func vipsSavePNGToBuffer(in *C.VipsImage, params PngExportParams) ([]byte, error) {
+	incOpCounter("save_png_buffer")
+	return vipsSaveToBuffer(createPngSaveParams(in, params))
+}
 
+func createWebpSaveParams(in *C.VipsImage, params WebpExportParams) C.struct_SaveParams {
 	p := C.create_save_params(C.WEBP)
 	p.inputImage = in
 	p.stripMetadata = C.int(boolToInt(params.StripMetadata))
@@ -444,15 +678,17 @@ func vipsSaveWebPToBuffer(in *C.VipsImage, params WebpExportParams) ([]byte, err
 
 	if params.IccProfile != "" {
 		p.webpIccProfile = C.CString(params.IccProfile)
-		defer C.free(unsafe.Pointer(p.webpIccProfile))
 	}
 
-	return vipsSaveToBuffer(p)
+	return p
 }
 
-func vipsSaveTIFFToBuffer(in *C.VipsImage, params TiffExportParams) ([]byte, error) {
-	incOpCounter("save_tiff_buffer")
+func vipsSaveWebPToBuffer(in *C.VipsImage, params WebpExportParams) ([]byte, error) {
+	incOpCounter("save_webp_buffer")
+	return vipsSaveToBuffer(createWebpSaveParams(in, params))
+}
 
+func createTiffSaveParams(in *C.VipsImage, params TiffExportParams) C.struct_SaveParams {
 	p := C.create_save_params(C.TIFF)
 	p.inputImage = in
 	p.stripMetadata = C.int(boolToInt(params.StripMetadata))
@@ -471,12 +707,15 @@ func vipsSaveTIFFToBuffer(in *C.VipsImage, params TiffExportParams) ([]byte, err
 	p.tiffTileHeight = C.int(tileHeight)
 	p.tiffTileWidth = C.int(tileWidth)
 
-	return vipsSaveToBuffer(p)
+	return p
 }
 
-func vipsSaveHEIFToBuffer(in *C.VipsImage, params HeifExportParams) ([]byte, error) {
-	incOpCounter("save_heif_buffer")
+func vipsSaveTIFFToBuffer(in *C.VipsImage, params TiffExportParams) ([]byte, error) {
+	incOpCounter("save_tiff_buffer")
+	return vipsSaveToBuffer(createTiffSaveParams(in, params))
+}
 
+func createHeifSaveParams(in *C.VipsImage, params HeifExportParams) C.struct_SaveParams {
 	p := C.create_save_params(C.HEIF)
 	p.inputImage = in
 	p.outputFormat = C.HEIF
@@ -485,12 +724,15 @@ func vipsSaveHEIFToBuffer(in *C.VipsImage, params HeifExportParams) ([]byte, err
 	p.heifBitdepth = C.int(params.Bitdepth)
 	p.heifEffort = C.int(params.Effort)
 
-	return vipsSaveToBuffer(p)
+	return p
 }
 
-func vipsSaveAVIFToBuffer(in *C.VipsImage, params AvifExportParams) ([]byte, error) {
+func vipsSaveHEIFToBuffer(in *C.VipsImage, params HeifExportParams) ([]byte, error) {
 	incOpCounter("save_heif_buffer")
+	return vipsSaveToBuffer(createHeifSaveParams(in, params))
+}
 
+func createAvifSaveParams(in *C.VipsImage, params AvifExportParams) C.struct_SaveParams {
 	// Speed was deprecated but we want to avoid breaking code that still uses it:
 	effort := params.Effort
 	if params.Speed != 0 {
@@ -506,12 +748,15 @@ func vipsSaveAVIFToBuffer(in *C.VipsImage, params AvifExportParams) ([]byte, err
 	p.heifBitdepth = C.int(params.Bitdepth)
 	p.heifEffort = C.int(effort)
 
-	return vipsSaveToBuffer(p)
+	return p
 }
 
-func vipsSaveJP2KToBuffer(in *C.VipsImage, params Jp2kExportParams) ([]byte, error) {
-	incOpCounter("save_jp2k_buffer")
+func vipsSaveAVIFToBuffer(in *C.VipsImage, params AvifExportParams) ([]byte, error) {
+	incOpCounter("save_heif_buffer")
+	return vipsSaveToBuffer(createAvifSaveParams(in, params))
+}
 
+func createJp2kSaveParams(in *C.VipsImage, params Jp2kExportParams) C.struct_SaveParams {
 	p := C.create_save_params(C.JP2K)
 	p.inputImage = in
 	p.outputFormat = C.JP2K
@@ -521,12 +766,15 @@ func vipsSaveJP2KToBuffer(in *C.VipsImage, params Jp2kExportParams) ([]byte, err
 	p.jp2kTileHeight = C.int(params.TileHeight)
 	p.jpegSubsample = C.VipsForeignSubsample(params.SubsampleMode)
 
-	return vipsSaveToBuffer(p)
+	return p
 }
 
-func vipsSaveGIFToBuffer(in *C.VipsImage, params GifExportParams) ([]byte, error) {
-	incOpCounter("save_gif_buffer")
+func vipsSaveJP2KToBuffer(in *C.VipsImage, params Jp2kExportParams) ([]byte, error) {
+	incOpCounter("save_jp2k_buffer")
+	return vipsSaveToBuffer(createJp2kSaveParams(in, params))
+}
 
+func createGifSaveParams(in *C.VipsImage, params GifExportParams) C.struct_SaveParams {
 	p := C.create_save_params(C.GIF)
 	p.inputImage = in
 	p.quality = C.int(params.Quality)
@@ -534,12 +782,15 @@ func vipsSaveGIFToBuffer(in *C.VipsImage, params GifExportParams) ([]byte, error
 	p.gifEffort = C.int(params.Effort)
 	p.gifBitdepth = C.int(params.Bitdepth)
 
-	return vipsSaveToBuffer(p)
+	return p
 }
 
-func vipsSaveJxlToBuffer(in *C.VipsImage, params JxlExportParams) ([]byte, error) {
-	incOpCounter("save_jxl_buffer")
+func vipsSaveGIFToBuffer(in *C.VipsImage, params GifExportParams) ([]byte, error) {
+	incOpCounter("save_gif_buffer")
+	return vipsSaveToBuffer(createGifSaveParams(in, params))
+}
 
+func createJxlSaveParams(in *C.VipsImage, params JxlExportParams) C.struct_SaveParams {
 	p := C.create_save_params(C.JXL)
 	p.inputImage = in
 	p.outputFormat = C.JXL
@@ -549,15 +800,15 @@ func vipsSaveJxlToBuffer(in *C.VipsImage, params JxlExportParams) ([]byte, error
 	p.jxlDistance = C.double(params.Distance)
 	p.jxlEffort = C.int(params.Effort)
 
-	return vipsSaveToBuffer(p)
+	return p
 }
 
-func vipsSaveMagickToBuffer(in *C.VipsImage, params MagickExportParams) ([]byte, error) {
-	incOpCounter("save_magick_buffer")
+func vipsSaveJxlToBuffer(in *C.VipsImage, params JxlExportParams) ([]byte, error) {
+	incOpCounter("save_jxl_buffer")
+	return vipsSaveToBuffer(createJxlSaveParams(in, params))
+}
 
-	if params.Format == "" {
-		return nil, errors.New("magick format required")
-	}
+func createMagickSaveParams(in *C.VipsImage, params MagickExportParams) C.struct_SaveParams {
 	p := C.create_save_params(C.MAGICK)
 	p.inputImage = in
 	p.outputFormat = C.MAGICK
@@ -567,10 +818,33 @@ func vipsSaveMagickToBuffer(in *C.VipsImage, params MagickExportParams) ([]byte,
 	p.magickOptimizeGifTransparency = C.int(boolToInt(params.OptimizeGifTransparency))
 	p.magickBitDepth = C.int(params.BitDepth)
 
-	return vipsSaveToBuffer(p)
+	return p
+}
+
+func vipsSaveMagickToBuffer(in *C.VipsImage, params MagickExportParams) ([]byte, error) {
+	incOpCounter("save_magick_buffer")
+
+	if params.Format == "" {
+		return nil, errors.New("magick format required")
+	}
+
+	return vipsSaveToBuffer(createMagickSaveParams(in, params))
+}
+
+// freeSaveParams releases the C strings allocated by the create*SaveParams builders.
+// It must be given a copy taken before saving, as libvips may swap in static strings.
+func freeSaveParams(params C.struct_SaveParams) {
+	if params.webpIccProfile != nil {
+		C.free(unsafe.Pointer(params.webpIccProfile))
+	}
+	if params.magickFormat != nil {
+		C.free(unsafe.Pointer(params.magickFormat))
+	}
 }
 
 func vipsSaveToBuffer(params C.struct_SaveParams) ([]byte, error) {
+	defer freeSaveParams(params)
+
 	if err := C.save_to_buffer(&params); err != 0 {
 		return nil, handleSaveBufferError(params.outputBuffer)
 	}
@@ -74,10 +74,14 @@ typedef struct LoadParams {
 
 LoadParams create_load_params(ImageType inputFormat);
 int load_from_buffer(LoadParams *params, void *buf, size_t len);
+int load_from_source(LoadParams *params, VipsSource *source);
+int load_from_file(const char *filename, VipsImage **out);
+const char *find_file_loader(const char *filename);
 
 typedef struct SaveParams {
   VipsImage *inputImage;
   void *outputBuffer;
+  VipsTarget *outputTarget;
   ImageType outputFormat;
   size_t outputLen;
 
@@ -147,4 +151,5 @@ typedef struct SaveParams {
 
 SaveParams create_save_params(ImageType outputFormat);
 int save_to_buffer(SaveParams *params);
+int save_to_target(SaveParams *params);
 
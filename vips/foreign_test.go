@@ -1,6 +1,7 @@
 package vips
 
 import (
+	"errors"
 	"os"
 	"testing"
 
@@ -231,3 +232,158 @@ func TestIsPDF(t *testing.T) {
 		assert.False(t, isPDF(buf))
 	})
 }
+
+func Test_LoadImageFromFile__Options(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	// NumPages is not understood by the JPEG loader and must not break the option string
+	params := NewImportParams()
+	params.NumPages.Set(1)
+	params.Access.Set(AccessSequential)
+
+	img, err := LoadImageFromFile(resources+"jpg-24bit.jpg", params)
+	require.NoError(t, err)
+	defer img.Close()
+	assert.Equal(t, ImageTypeJPEG, img.Format())
+	assert.Equal(t, ImageTypeJPEG, img.OriginalFormat())
+}
+
+func Test_LoadImageFromFile__Missing(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	img, err := LoadImageFromFile(resources+"does-not-exist.jpg", nil)
+	assert.Nil(t, img)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func Test_LoadOptionString(t *testing.T) {
+	p := NewImportParams()
+	p.NumPages.Set(2)
+	p.JpegShrinkFactor.Set(2)
+	p.Access.Set(AccessSequential)
+
+	assert.Equal(t, "fail=TRUE,shrink=2,access=sequential", p.loadOptionString(ImageTypeJPEG))
+	assert.Equal(t, "n=2,access=sequential", p.loadOptionString(ImageTypeGIF))
+	assert.Equal(t, "", p.loadOptionString(ImageTypeJXL))
+}
+
+func Test_SaveToFile(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	img, err := NewImageFromFile(resources + "png-24bit.png")
+	require.NoError(t, err)
+	defer img.Close()
+
+	dir := t.TempDir()
+
+	t.Run("extension", func(t *testing.T) {
+		path := dir + "/out.jpg"
+		metadata, err := img.SaveToFile(path, nil)
+		require.NoError(t, err)
+		assert.Equal(t, ImageTypeJPEG, metadata.Format)
+
+		saved, err := NewImageFromFile(path)
+		require.NoError(t, err)
+		defer saved.Close()
+		assert.Equal(t, ImageTypeJPEG, saved.Format())
+		assert.Equal(t, img.Width(), saved.Width())
+	})
+
+	t.Run("params", func(t *testing.T) {
+		path := dir + "/out.bin"
+		metadata, err := img.SaveToFile(path, NewWebpExportParams())
+		require.NoError(t, err)
+		assert.Equal(t, ImageTypeWEBP, metadata.Format)
+
+		buf, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, ImageTypeWEBP, DetermineImageType(buf))
+	})
+
+	t.Run("unknown extension", func(t *testing.T) {
+		_, err := img.SaveToFile(dir+"/out.unknown", nil)
+		assert.True(t, errors.Is(err, ErrUnsupportedSaveFormat))
+	})
+}
+
+func Test_LoadImage__Limits(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	buf, err := os.ReadFile(resources + "jpg-24bit.jpg")
+	require.NoError(t, err)
+
+	ref, err := NewImageFromBuffer(buf)
+	require.NoError(t, err)
+	width, height := ref.Width(), ref.Height()
+	ref.Close()
+
+	tests := []struct {
+		name      string
+		set       func(p *ImportParams)
+		dimension LimitDimension
+	}{
+		{"width", func(p *ImportParams) { p.MaxWidth.Set(width - 1) }, LimitWidth},
+		{"height", func(p *ImportParams) { p.MaxHeight.Set(height - 1) }, LimitHeight},
+		{"pixels", func(p *ImportParams) { p.MaxPixels.Set(width*height - 1) }, LimitPixels},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := NewImportParams()
+			tt.set(params)
+
+			img, err := LoadImageFromBuffer(buf, params)
+			assert.Nil(t, img)
+			require.True(t, errors.Is(err, ErrImageTooLarge))
+
+			var tooLarge *ImageTooLargeError
+			require.True(t, errors.As(err, &tooLarge))
+			assert.Equal(t, tt.dimension, tooLarge.Dimension)
+
+			img, err = LoadImageFromFile(resources+"jpg-24bit.jpg", params)
+			assert.Nil(t, img)
+			assert.True(t, errors.Is(err, ErrImageTooLarge))
+
+			img, err = LoadThumbnailFromBuffer(buf, 10, 10, InterestingNone, SizeDown, params)
+			assert.Nil(t, img)
+			assert.True(t, errors.Is(err, ErrImageTooLarge))
+
+			img, err = LoadThumbnailFromFile(resources+"jpg-24bit.jpg", 10, 10, InterestingNone, SizeDown, params)
+			assert.Nil(t, img)
+			assert.True(t, errors.Is(err, ErrImageTooLarge))
+		})
+	}
+
+	t.Run("within limits", func(t *testing.T) {
+		params := NewImportParams()
+		params.MaxWidth.Set(width)
+		params.MaxHeight.Set(height)
+		params.MaxPixels.Set(width * height)
+		params.MaxPages.Set(1)
+
+		img, err := LoadImageFromBuffer(buf, params)
+		require.NoError(t, err)
+		img.Close()
+
+		img, err = LoadThumbnailFromBuffer(buf, 10, 10, InterestingNone, SizeDown, params)
+		require.NoError(t, err)
+		assert.Equal(t, 10, img.Width())
+		img.Close()
+	})
+}
+
+func Test_LoadImage__MaxPages(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	params := NewImportParams()
+	params.NumPages.Set(-1)
+	params.MaxPages.Set(1)
+
+	img, err := LoadImageFromFile(resources+"gif-animated.gif", params)
+	assert.Nil(t, img)
+
+	var tooLarge *ImageTooLargeError
+	require.True(t, errors.As(err, &tooLarge))
+	assert.Equal(t, LimitPages, tooLarge.Dimension)
+	assert.Greater(t, tooLarge.Value, 1)
+}
@@ -451,8 +451,12 @@ func vipsGenAffine(input *C.VipsImage, matrix []float64, opts *AffineOptions) (*
 		}
 		if opts.Oarea != nil {
 			cOpts.has_oarea = 1
-			pinner.Pin(&opts.Oarea[0])
-			cOpts.oarea = (*C.int)(unsafe.Pointer(&opts.Oarea[0]))
+			tmp_oarea := make([]C.int, len(opts.Oarea))
+			for i, v := range opts.Oarea {
+				tmp_oarea[i] = C.int(v)
+			}
+			pinner.Pin(&tmp_oarea[0])
+			cOpts.oarea = &tmp_oarea[0]
 			cOpts.oarea_n = C.int(len(opts.Oarea))
 		}
 		if opts.Odx != nil {
@@ -24,4 +24,25 @@ void vips_unset_logging_handler(void) {
    exit. */
 void vips_default_logging_handler(void) {
   g_log_set_default_handler(g_log_default_handler, NULL);
-}
\ No newline at end of file
+}
+// Operation blocking needs libvips 8.13+, older versions report failure so the
+// caller can refuse to run with weaker protection than it asked for.
+int block_untrusted_set(gboolean state) {
+#if (VIPS_MAJOR_VERSION > 8) || \
+    (VIPS_MAJOR_VERSION == 8 && VIPS_MINOR_VERSION >= 13)
+  vips_block_untrusted_set(state);
+  return 0;
+#else
+  return 1;
+#endif
+}
+
+int operation_block_set(const char *name, gboolean state) {
+#if (VIPS_MAJOR_VERSION > 8) || \
+    (VIPS_MAJOR_VERSION == 8 && VIPS_MINOR_VERSION >= 13)
+  vips_operation_block_set(name, state);
+  return 0;
+#else
+  return 1;
+#endif
+}
@@ -44,6 +44,8 @@ var (
 	once                sync.Once
 	typeLoaders         = make(map[string]ImageType)
 	supportedImageTypes = make(map[ImageType]bool)
+	supportedSaveTypes  = make(map[ImageType]bool)
+	allowedLoaders      map[ImageType]bool
 )
 
 // Config allows fine-tuning of libvips library
@@ -55,6 +57,14 @@ type Config struct {
 	ReportLeaks      bool
 	CacheTrace       bool
 	CollectStats     bool
+
+	// AllowedLoaders restricts decoding to the listed image types, anything else fails to load
+	// with ErrUnsupportedImageFormat. With libvips 8.13+ all other loaders are blocked in libvips too.
+	// Leave empty to allow every supported type.
+	AllowedLoaders []ImageType
+	// BlockUntrusted blocks the libvips operations marked as untrusted, such as loaders built
+	// on large third-party libraries which are not fuzzed. Requires libvips 8.13+.
+	BlockUntrusted bool
 }
 
 // Startup sets up the libvips support and ensures the versions are correct. Pass in nil for
@@ -133,6 +143,17 @@ func Startup(config *Config) error {
 		}
 
 		C.vips_cache_set_trace(toGboolean(config.CacheTrace))
+
+		if len(config.AllowedLoaders) > 0 {
+			setAllowedLoaders(config.AllowedLoaders)
+		}
+
+		// Applied after the allowlist, which unblocks loaders, so untrusted ones stay blocked
+		if config.BlockUntrusted {
+			if C.block_untrusted_set(toGboolean(true)) != 0 {
+				return errors.New("blocking untrusted operations requires libvips version 8.13+")
+			}
+		}
 	} else {
 		C.vips_concurrency_set(defaultConcurrencyLevel)
 		C.vips_cache_set_max(defaultMaxCacheSize)
@@ -283,6 +304,71 @@ func initTypes() {
 			if supportedImageTypes[k] {
 				govipsLog("govips", LogLevelInfo, fmt.Sprintf("registered image type loader type=%s", v))
 			}
+
+			cSaveFunc := C.CString(v + "save")
+			//noinspection GoDeferInLoop
+			defer freeCString(cSaveFunc)
+
+			supportedSaveTypes[k] = int(C.vips_type_find(cType, cSaveFunc)) != 0
+		}
+
+		// GIF falls back to magicksave on libvips without a native gifsave
+		if !supportedSaveTypes[ImageTypeGIF] {
+			supportedSaveTypes[ImageTypeGIF] = supportedSaveTypes[ImageTypeMagick]
 		}
 	})
 }
+
+// vipsOperationExists reports whether the libvips in use has the operation with the given nickname
+func vipsOperationExists(name string) bool {
+	cType := C.CString("VipsOperation")
+	defer freeCString(cType)
+	cName := C.CString(name)
+	defer freeCString(cName)
+	return C.vips_type_find(cType, cName) != 0
+}
+
+// setAllowedLoaders restricts loading to the given types, or lifts the restriction when types is empty.
+// Where libvips supports it, every other loader is blocked as well so content sniffed by libvips
+// itself cannot reach them either.
+func setAllowedLoaders(types []ImageType) {
+	cBase := C.CString("VipsForeignLoad")
+	defer freeCString(cBase)
+
+	if len(types) == 0 {
+		allowedLoaders = nil
+		C.operation_block_set(cBase, toGboolean(false))
+		return
+	}
+
+	allowedLoaders = make(map[ImageType]bool)
+	for _, t := range types {
+		allowedLoaders[t] = true
+	}
+
+	if C.operation_block_set(cBase, toGboolean(true)) != 0 {
+		govipsLog("govips", LogLevelWarning, "libvips 8.13+ is required to block loaders, only govips will enforce AllowedLoaders")
+		return
+	}
+
+	for _, t := range types {
+		// BMP and friends are decoded by the magick loader
+		if isNeedToChangeLoaderToMagick(t) {
+			t = ImageTypeMagick
+		}
+		name, ok := ImageTypes[t]
+		if !ok {
+			continue
+		}
+		for _, suffix := range []string{"load", "load_buffer", "load_source"} {
+			cName := C.CString(name + suffix)
+			C.operation_block_set(cName, toGboolean(false))
+			freeCString(cName)
+		}
+	}
+}
+
+// isLoaderAllowed checks whether an image sniffed as imageType may be decoded under Config.AllowedLoaders
+func isLoaderAllowed(imageType ImageType) bool {
+	return allowedLoaders == nil || allowedLoaders[imageType]
+}
@@ -23,4 +23,6 @@ static void null_logging_handler(const gchar *log_domain,
 
 void vips_set_logging_handler(void);
 void vips_unset_logging_handler(void);
-void vips_default_logging_handler(void);
\ No newline at end of file
+void vips_default_logging_handler(void);
+int block_untrusted_set(gboolean state);
+int operation_block_set(const char *name, gboolean state);
@@ -1,8 +1,11 @@
 package vips
 
 import (
+	"errors"
+	"os"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -12,3 +15,36 @@ func TestInitConfig(t *testing.T) {
 	running = false
 	require.NoError(t, startupIfNeeded())
 }
+
+func TestAllowedLoaders(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	setAllowedLoaders([]ImageType{ImageTypePNG, ImageTypeWEBP})
+	defer setAllowedLoaders(nil)
+
+	png, err := NewImageFromFile(resources + "png-24bit.png")
+	require.NoError(t, err)
+	png.Close()
+
+	jpegBuf, err := os.ReadFile(resources + "jpg-24bit.jpg")
+	require.NoError(t, err)
+
+	img, err := NewImageFromBuffer(jpegBuf)
+	assert.Nil(t, img)
+	assert.True(t, errors.Is(err, ErrUnsupportedImageFormat))
+
+	img, err = NewImageFromFile(resources + "jpg-24bit.jpg")
+	assert.Nil(t, img)
+	assert.True(t, errors.Is(err, ErrUnsupportedImageFormat))
+
+	img, err = NewThumbnailFromBuffer(jpegBuf, 100, 100, InterestingNone)
+	assert.Nil(t, img)
+	assert.True(t, errors.Is(err, ErrUnsupportedImageFormat))
+
+	// Saving is not affected by the loader allowlist
+	png, err = NewImageFromFile(resources + "png-24bit.png")
+	require.NoError(t, err)
+	defer png.Close()
+	_, _, err = png.ExportJpeg(nil)
+	require.NoError(t, err)
+}
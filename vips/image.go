@@ -4,6 +4,7 @@ package vips
 import "C"
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"image"
@@ -12,11 +13,11 @@ import (
 	_ "image/jpeg"
 	_ "image/png"
 	"io"
-	"os"
 	"runtime"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 	"unsafe"
 
 	_ "golang.org/x/image/webp"
@@ -41,9 +42,17 @@ type ImageRef struct {
 	lock                sync.Mutex
 	preMultiplication   *PreMultiplicationState
 	optimizedIccProfile string
+	progress            ProgressFunc
 }
 
+// ProgressFunc receives the progress of computing the pixels of an image, as a percentage
+// and the estimated time remaining. It is called with 0 when the computation starts and with
+// 100 once it is done.
+type ProgressFunc func(percent int, eta time.Duration)
+
 // ImageMetadata is a data structure holding the width, height, orientation and other metadata of the picture.
+// The fields after Pages are only filled in by ProbeBuffer and ProbeFile, whose Format is the
+// format of the source, e.g. BMP rather than the magick loader used to read it.
 type ImageMetadata struct {
 	Format      ImageType
 	Width       int
@@ -51,6 +60,14 @@ type ImageMetadata struct {
 	Colorspace  Interpretation
 	Orientation int
 	Pages       int
+
+	Bands         int
+	BandFormat    BandFormat
+	PageHeight    int
+	Loader        string
+	HasICCProfile bool
+	HasExif       bool
+	HasXMP        bool
 }
 
 type Parameter struct {
@@ -117,6 +134,14 @@ type ImportParams struct {
 	HeifThumbnail    BoolParameter
 	SvgUnlimited     BoolParameter
 	Access           IntParameter
+
+	// Limits are checked once the header is parsed, before any pixels are decoded.
+	// MaxPixels applies to all loaded pages together, MaxHeight to a single page
+	// and MaxPages to the number of pages loaded.
+	MaxPixels IntParameter
+	MaxWidth  IntParameter
+	MaxHeight IntParameter
+	MaxPages  IntParameter
 }
 
 // NewImportParams creates default ImportParams
@@ -157,11 +182,56 @@ func (i *ImportParams) OptionString() string {
 		values = append(values, "thumbnail="+boolToStr(v.Get()))
 	}
 	if v := i.Access; v.IsSet() {
-		values = append(values, "access="+strconv.Itoa(v.Get()))
+		values = append(values, "access="+accessToStr(v.Get()))
 	}
 	return strings.Join(values, ",")
 }
 
+// loaderOptions lists the option names each file loader accepts, mirroring the
+// set_*load_options functions used for buffers. Options a loader does not know
+// would make libvips reject the whole option string.
+var loaderOptions = map[ImageType][]string{
+	ImageTypeJPEG:   {"autorotate", "fail", "shrink", "access"},
+	ImageTypePNG:    {"fail", "access"},
+	ImageTypeWEBP:   {"page", "n", "scale", "access"},
+	ImageTypeTIFF:   {"autorotate", "page", "n", "access"},
+	ImageTypeGIF:    {"page", "n", "access"},
+	ImageTypePDF:    {"page", "n", "dpi", "access"},
+	ImageTypeSVG:    {"unlimited", "dpi", "access"},
+	ImageTypeHEIF:   {"autorotate", "thumbnail", "page", "n", "access"},
+	ImageTypeAVIF:   {"autorotate", "thumbnail", "page", "n", "access"},
+	ImageTypeJP2K:   {"page", "access"},
+	ImageTypeMagick: {"page", "n", "access"},
+}
+
+// loadOptionString is OptionString restricted to the options understood by the loader for format.
+func (i *ImportParams) loadOptionString(format ImageType) string {
+	allowed := loaderOptions[format]
+
+	var values []string
+	for _, option := range strings.Split(i.OptionString(), ",") {
+		name, _, _ := strings.Cut(option, "=")
+		for _, a := range allowed {
+			if name == a {
+				values = append(values, option)
+				break
+			}
+		}
+	}
+	return strings.Join(values, ",")
+}
+
+func accessToStr(v int) string {
+	switch v {
+	case AccessSequential:
+		return "sequential"
+	case AccessSequentialUnbuffered:
+		return "sequential-unbuffered"
+	default:
+		return "random"
+	}
+}
+
 func boolToStr(v bool) string {
 	if v {
 		return "TRUE"
@@ -460,12 +530,32 @@ func NewMagickExportParams() *MagickExportParams {
 
 // NewImageFromReader loads an ImageRef from the given reader
 func NewImageFromReader(r io.Reader) (*ImageRef, error) {
-	buf, err := io.ReadAll(r)
+	return LoadImageFromReader(r, nil)
+}
+
+// LoadImageFromReader streams an image from the given reader and creates a new ImageRef.
+// The input is not buffered in Go memory: libvips pulls bytes from the reader as it decodes,
+// so the reader must remain readable until the image has been exported or closed.
+// If the reader also implements io.Seeker, libvips can seek instead of buffering the input itself.
+// Combine with AccessSequential to process large images with bounded memory.
+func LoadImageFromReader(r io.Reader, params *ImportParams) (*ImageRef, error) {
+	if err := startupIfNeeded(); err != nil {
+		return nil, err
+	}
+
+	if params == nil {
+		params = NewImportParams()
+	}
+
+	vipsImage, currentFormat, originalFormat, err := vipsLoadFromReader(r, params)
 	if err != nil {
 		return nil, err
 	}
 
-	return NewImageFromBuffer(buf)
+	ref := newImageRef(vipsImage, currentFormat, originalFormat, nil)
+
+	govipsLog("govips", LogLevelDebug, fmt.Sprintf("created imageRef %p", ref))
+	return ref, nil
 }
 
 // NewImageFromFile loads an image from file and creates a new ImageRef
@@ -473,15 +563,29 @@ func NewImageFromFile(file string) (*ImageRef, error) {
 	return LoadImageFromFile(file, nil)
 }
 
-// LoadImageFromFile loads an image from file and creates a new ImageRef
+// LoadImageFromFile loads an image from file and creates a new ImageRef.
+// The file is read by the libvips file loaders rather than copied into Go memory,
+// so combine with AccessSequential or AccessRandom to control how it is accessed on disk.
 func LoadImageFromFile(file string, params *ImportParams) (*ImageRef, error) {
-	buf, err := os.ReadFile(file)
-	if err != nil {
+	if err := startupIfNeeded(); err != nil {
 		return nil, err
 	}
 
+	if params == nil {
+		params = NewImportParams()
+	}
+
 	govipsLog("govips", LogLevelDebug, fmt.Sprintf("creating imageRef from file %s", file))
-	return LoadImageFromBuffer(buf, params)
+
+	vipsImage, currentFormat, originalFormat, err := vipsLoadFromFile(file, params)
+	if err != nil {
+		return nil, err
+	}
+
+	ref := newImageRef(vipsImage, currentFormat, originalFormat, nil)
+
+	govipsLog("govips", LogLevelDebug, fmt.Sprintf("created imageRef %p", ref))
+	return ref, nil
 }
 
 // NewImageFromBuffer loads an image buffer and creates a new Image
@@ -510,6 +614,41 @@ func LoadImageFromBuffer(buf []byte, params *ImportParams) (*ImageRef, error) {
 	return ref, nil
 }
 
+// LoadImageFromBufferContext loads an image buffer like LoadImageFromBuffer, returning ctx.Err() once ctx is done.
+// libvips decodes pixels lazily, so pass the same ctx to the Export*Context call consuming the image
+// to bound the decoding work as well.
+func LoadImageFromBufferContext(ctx context.Context, buf []byte, params *ImportParams) (*ImageRef, error) {
+	return loadImageWithContext(ctx, func() (*ImageRef, error) {
+		return LoadImageFromBuffer(buf, params)
+	})
+}
+
+// LoadImageFromFileContext loads an image from file like LoadImageFromFile, returning ctx.Err() once ctx is done.
+// libvips decodes pixels lazily, so pass the same ctx to the Export*Context call consuming the image
+// to bound the decoding work as well.
+func LoadImageFromFileContext(ctx context.Context, file string, params *ImportParams) (*ImageRef, error) {
+	return loadImageWithContext(ctx, func() (*ImageRef, error) {
+		return LoadImageFromFile(file, params)
+	})
+}
+
+func loadImageWithContext(ctx context.Context, load func() (*ImageRef, error)) (*ImageRef, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ref, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		ref.Close()
+		return nil, err
+	}
+	return ref, nil
+}
+
 // NewThumbnailFromFile loads an image from file and creates a new ImageRef with thumbnail crop
 func NewThumbnailFromFile(file string, width, height int, crop Interesting) (*ImageRef, error) {
 	return LoadThumbnailFromFile(file, width, height, crop, SizeBoth, nil)
@@ -525,7 +664,8 @@ func NewThumbnailWithSizeFromFile(file string, width, height int, crop Interesti
 	return LoadThumbnailFromFile(file, width, height, crop, size, nil)
 }
 
-// LoadThumbnailFromFile loads an image from file and creates a new ImageRef with thumbnail crop and size
+// LoadThumbnailFromFile loads an image from file and creates a new ImageRef with thumbnail crop and size.
+// The limits in params apply to the source image, before it is shrunk.
 func LoadThumbnailFromFile(file string, width, height int, crop Interesting, size Size, params *ImportParams) (*ImageRef, error) {
 	if err := startupIfNeeded(); err != nil {
 		return nil, err
@@ -547,7 +687,8 @@ func NewThumbnailWithSizeFromBuffer(buf []byte, width, height int, crop Interest
 	return LoadThumbnailFromBuffer(buf, width, height, crop, size, nil)
 }
 
-// LoadThumbnailFromBuffer loads an image buffer and creates a new Image with thumbnail crop and size
+// LoadThumbnailFromBuffer loads an image buffer and creates a new Image with thumbnail crop and size.
+// The limits in params apply to the source image, before it is shrunk.
 func LoadThumbnailFromBuffer(buf []byte, width, height int, crop Interesting, size Size, params *ImportParams) (*ImageRef, error) {
 	if err := startupIfNeeded(); err != nil {
 		return nil, err
@@ -733,6 +874,13 @@ func finalizeImage(ref *ImageRef) {
 	ref.Close()
 }
 
+// SetProgressFunc sets fn to be called as the pixels of the image are computed by the
+// Export*, ToBytes and ToGoImage methods. Use nil to remove it. fn runs on the thread
+// driving the computation, so it should return quickly.
+func (r *ImageRef) SetProgressFunc(fn ProgressFunc) {
+	r.progress = fn
+}
+
 // Close manually closes the image and frees the memory. Calling Close() is optional.
 // Images are automatically closed by GC. However, in high volume applications the GC
 // can't keep up with the amount of memory, so you might want to manually close the images.
@@ -0,0 +1,163 @@
+package vips
+
+// #include "image.h"
+import "C"
+
+import (
+	"errors"
+	"runtime"
+)
+
+// XYZToLab converts an XYZ image to Lab relative to white
+func (r *ImageRef) XYZToLab(white WhitePoint) error {
+	return r.convertColor(func(in *C.VipsImage) (*C.VipsImage, error) {
+		return vipsGenXYZ2Lab(in, &XYZ2LabOptions{Temp: white[:]})
+	})
+}
+
+// LabToXYZ converts a Lab image to XYZ relative to white
+func (r *ImageRef) LabToXYZ(white WhitePoint) error {
+	return r.convertColor(func(in *C.VipsImage) (*C.VipsImage, error) {
+		return vipsGenLab2XYZ(in, &Lab2XYZOptions{Temp: white[:]})
+	})
+}
+
+// LabToLCh converts a Lab image to LCh, with hue in degrees
+func (r *ImageRef) LabToLCh() error {
+	return r.convertColor(vipsGenLab2LCh)
+}
+
+// LChToLab converts an LCh image to Lab
+func (r *ImageRef) LChToLab() error {
+	return r.convertColor(vipsGenLCh2Lab)
+}
+
+// XYZToYxy converts an XYZ image to Yxy
+func (r *ImageRef) XYZToYxy() error {
+	return r.convertColor(vipsGenXYZ2Yxy)
+}
+
+// YxyToXYZ converts a Yxy image to XYZ
+func (r *ImageRef) YxyToXYZ() error {
+	return r.convertColor(vipsGenYxy2XYZ)
+}
+
+// XYZToOklab converts an XYZ image to Oklab. Requires libvips 8.16 or later.
+func (r *ImageRef) XYZToOklab() error {
+	return r.convertColor(vipsGenXYZ2Oklab)
+}
+
+// OklabToXYZ converts an Oklab image to XYZ. Requires libvips 8.16 or later.
+func (r *ImageRef) OklabToXYZ() error {
+	return r.convertColor(vipsGenOklab2XYZ)
+}
+
+// OklabToOklch converts an Oklab image to Oklch, with hue in degrees. Requires libvips 8.16 or later.
+func (r *ImageRef) OklabToOklch() error {
+	return r.convertColor(vipsGenOklab2Oklch)
+}
+
+// OklchToOklab converts an Oklch image to Oklab. Requires libvips 8.16 or later.
+func (r *ImageRef) OklchToOklab() error {
+	return r.convertColor(vipsGenOklch2Oklab)
+}
+
+func (r *ImageRef) convertColor(fn func(in *C.VipsImage) (*C.VipsImage, error)) error {
+	defer runtime.KeepAlive(r)
+	out, err := fn(r.image)
+	if err != nil {
+		return err
+	}
+	r.setImage(out)
+	return nil
+}
+
+// DeltaEFormula selects how DeltaE measures perceptual colour difference
+type DeltaEFormula int
+
+// DeltaEFormula enum
+const (
+	// DeltaE76 is the Euclidean distance in Lab space (CIE76)
+	DeltaE76 DeltaEFormula = iota
+	// DeltaECMC is the CMC(l:c) 1:1 formula
+	DeltaECMC
+	// DeltaE2000 is the CIEDE2000 formula, the most perceptually uniform
+	DeltaE2000
+)
+
+// DeltaE returns a one band float image holding the colour difference between each pixel
+// of the image and the matching pixel of other, measured with formula. Both images are
+// converted to Lab as needed and any alpha is ignored. The images must be the same size.
+func (r *ImageRef) DeltaE(other *ImageRef, formula DeltaEFormula) (*ImageRef, error) {
+	defer runtime.KeepAlive(r)
+	defer runtime.KeepAlive(other)
+	if r.Width() != other.Width() || r.Height() != other.Height() {
+		return nil, errors.New("images must be the same size to compare colours")
+	}
+
+	left, err := vipsToLab(r.image)
+	if err != nil {
+		return nil, err
+	}
+	defer clearImage(left)
+
+	right, err := vipsToLab(other.image)
+	if err != nil {
+		return nil, err
+	}
+	defer clearImage(right)
+
+	var out *C.VipsImage
+	switch formula {
+	case DeltaE76:
+		out, err = vipsGenDE76(left, right)
+	case DeltaECMC:
+		out, err = vipsGenDECMC(left, right)
+	case DeltaE2000:
+		out, err = vipsGenDE00(left, right)
+	default:
+		return nil, errors.New("unknown delta E formula")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newImageRef(out, ImageTypeUnknown, ImageTypeUnknown, nil), nil
+}
+
+// MeanDeltaE returns the average and maximum CIEDE2000 colour difference between the
+// image and other. A difference below about 1 is imperceptible and below about 2 is
+// only noticeable on close inspection.
+func (r *ImageRef) MeanDeltaE(other *ImageRef) (float64, float64, error) {
+	diff, err := r.DeltaE(other, DeltaE2000)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer diff.Close()
+
+	avg, err := diff.Average()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	max, _, _, err := vipsMax(diff.image)
+	if err != nil {
+		return 0, 0, err
+	}
+	return avg, max, nil
+}
+
+// vipsToLab converts in to a three band Lab image
+func vipsToLab(in *C.VipsImage) (*C.VipsImage, error) {
+	lab, err := vipsToColorSpace(in, InterpretationLAB)
+	if err != nil {
+		return nil, err
+	}
+	if lab.Bands <= 3 {
+		return lab, nil
+	}
+
+	n := 3
+	out, err := vipsGenExtractBand(lab, 0, &ExtractBandOptions{N: &n})
+	clearImage(lab)
+	return out, err
+}
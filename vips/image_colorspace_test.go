@@ -0,0 +1,109 @@
+package vips
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageRef_ColorScienceConversions(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	original, err := NewImageFromFile(resources + "png-24bit.png")
+	require.NoError(t, err)
+	defer original.Close()
+	require.NoError(t, original.ToColorSpace(InterpretationXYZ))
+
+	for name, roundTrip := range map[string]func(*ImageRef) error{
+		"lab d50": func(img *ImageRef) error {
+			if err := img.XYZToLab(WhitePointD50); err != nil {
+				return err
+			}
+			return img.LabToXYZ(WhitePointD50)
+		},
+		"lch": func(img *ImageRef) error {
+			if err := img.XYZToLab(WhitePointD65); err != nil {
+				return err
+			}
+			if err := img.LabToLCh(); err != nil {
+				return err
+			}
+			if err := img.LChToLab(); err != nil {
+				return err
+			}
+			return img.LabToXYZ(WhitePointD65)
+		},
+		"yxy": func(img *ImageRef) error {
+			if err := img.XYZToYxy(); err != nil {
+				return err
+			}
+			return img.YxyToXYZ()
+		},
+		"oklch": func(img *ImageRef) error {
+			if err := img.XYZToOklab(); err != nil {
+				return err
+			}
+			if err := img.OklabToOklch(); err != nil {
+				return err
+			}
+			if err := img.OklchToOklab(); err != nil {
+				return err
+			}
+			return img.OklabToXYZ()
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if name == "oklch" && !vipsOperationExists("XYZ2Oklab") {
+				t.Skip("Oklab conversions need libvips 8.16 or later")
+			}
+
+			img, err := original.Copy()
+			require.NoError(t, err)
+			defer img.Close()
+
+			require.NoError(t, roundTrip(img))
+			assert.Equal(t, original.Width(), img.Width())
+			assert.Equal(t, 3, img.Bands())
+			assert.InDelta(t, 0, maxAbsDifference(t, original, img), 0.01)
+		})
+	}
+}
+
+func TestImageRef_DeltaE(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	original, err := NewImageFromFile(resources + "png-24bit.png")
+	require.NoError(t, err)
+	defer original.Close()
+
+	shifted, err := original.Copy()
+	require.NoError(t, err)
+	defer shifted.Close()
+	require.NoError(t, shifted.Linear([]float64{1, 1, 1}, []float64{10, 0, -10}))
+	require.NoError(t, shifted.Cast(BandFormatUchar))
+
+	for _, formula := range []DeltaEFormula{DeltaE76, DeltaECMC, DeltaE2000} {
+		diff, err := original.DeltaE(shifted, formula)
+		require.NoError(t, err)
+		assert.Equal(t, 1, diff.Bands())
+		assert.Equal(t, original.Width(), diff.Width())
+		diff.Close()
+	}
+
+	avg, max, err := original.MeanDeltaE(original)
+	require.NoError(t, err)
+	assert.InDelta(t, 0, avg, 0.001)
+	assert.InDelta(t, 0, max, 0.001)
+
+	avg, max, err = original.MeanDeltaE(shifted)
+	require.NoError(t, err)
+	assert.Greater(t, avg, 0.5)
+	assert.GreaterOrEqual(t, max, avg)
+
+	other, err := Black(10, 10)
+	require.NoError(t, err)
+	defer other.Close()
+	_, err = original.DeltaE(other, DeltaE2000)
+	assert.Error(t, err)
+}
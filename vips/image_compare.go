@@ -0,0 +1,201 @@
+package vips
+
+// #include "image.h"
+import "C"
+
+import (
+	"errors"
+	"math"
+	"runtime"
+)
+
+// Metric is a measure of the similarity of two images used by Compare
+type Metric int
+
+// Metric enum
+const (
+	// MetricMSE is the mean squared error. 0 means the images are identical.
+	MetricMSE Metric = iota
+	// MetricPSNR is the peak signal to noise ratio in decibels. Higher is more similar
+	// and identical images give +Inf.
+	MetricPSNR
+	// MetricSSIM is the structural similarity index over an 11x11 Gaussian window.
+	// 1 means the images are identical.
+	MetricSSIM
+)
+
+// SSIM constants from Wang et al.
+const (
+	ssimSigma = 1.5
+	ssimK1    = 0.01
+	ssimK2    = 0.03
+	// ssimMinAmpl cuts the Gaussian mask of ssimSigma at a radius of 5, i.e. 11 taps wide.
+	// libvips keeps taps while exp(-x²/2σ²) >= min_ampl: 0.0039 at x=5 and 0.0003 at x=6.
+	ssimMinAmpl = 0.001
+)
+
+// Compare measures how similar a and b are with metric, averaged over all bands. The
+// images must have the same size and number of bands. The computation runs in libvips.
+func Compare(a, b *ImageRef, metric Metric) (float64, error) {
+	means, peak, err := compareBandMeans(a, b, metric)
+	if err != nil {
+		return 0, err
+	}
+
+	var sum float64
+	for _, m := range means {
+		sum += m
+	}
+	mean := sum / float64(len(means))
+
+	if metric == MetricPSNR {
+		return psnr(mean, peak), nil
+	}
+	return mean, nil
+}
+
+// CompareBands is like Compare but returns the metric for each band separately
+func CompareBands(a, b *ImageRef, metric Metric) ([]float64, error) {
+	means, peak, err := compareBandMeans(a, b, metric)
+	if err != nil {
+		return nil, err
+	}
+
+	if metric == MetricPSNR {
+		for i, mse := range means {
+			means[i] = psnr(mse, peak)
+		}
+	}
+	return means, nil
+}
+
+// compareBandMeans returns the per band mean of the error map for metric, and the peak
+// pixel value of the images' format
+func compareBandMeans(a, b *ImageRef, metric Metric) ([]float64, float64, error) {
+	defer runtime.KeepAlive(a)
+	defer runtime.KeepAlive(b)
+	if a.Width() != b.Width() || a.Height() != b.Height() || a.Bands() != b.Bands() {
+		return nil, 0, errors.New("images must have the same size and bands to compare")
+	}
+	peak := formatPeak(a.BandFormat())
+
+	s := &imageScratch{}
+	defer s.clear()
+
+	left := s.do(func() (*C.VipsImage, error) { return vipsGenCast(a.image, BandFormatFloat, nil) })
+	right := s.do(func() (*C.VipsImage, error) { return vipsGenCast(b.image, BandFormatFloat, nil) })
+
+	var errorMap *C.VipsImage
+	switch metric {
+	case MetricMSE, MetricPSNR:
+		diff := s.do(func() (*C.VipsImage, error) { return vipsGenSubtract(left, right) })
+		errorMap = s.do(func() (*C.VipsImage, error) { return vipsGenMultiply(diff, diff) })
+	case MetricSSIM:
+		errorMap = s.ssimMap(left, right, peak)
+	default:
+		return nil, 0, errors.New("unknown comparison metric")
+	}
+
+	stats := s.do(func() (*C.VipsImage, error) { return vipsGenStats(errorMap) })
+	if s.err != nil {
+		return nil, 0, s.err
+	}
+
+	// Row n+1 of the stats image holds band n, and column 4 is its mean
+	means := make([]float64, a.Bands())
+	for band := range means {
+		point, err := vipsGetPoint(stats, 1, 4, band+1)
+		if err != nil {
+			return nil, 0, err
+		}
+		means[band] = point[0]
+	}
+	return means, peak, nil
+}
+
+// ssimMap computes the SSIM of every pixel of two float images
+func (s *imageScratch) ssimMap(x, y *C.VipsImage, peak float64) *C.VipsImage {
+	c1 := math.Pow(ssimK1*peak, 2)
+	c2 := math.Pow(ssimK2*peak, 2)
+
+	precision := PrecisionFloat
+	minAmpl := ssimMinAmpl
+	blur := func(in *C.VipsImage) *C.VipsImage {
+		return s.do(func() (*C.VipsImage, error) {
+			return vipsGenGaussblur(in, ssimSigma, &GaussblurOptions{MinAmpl: &minAmpl, Precision: &precision})
+		})
+	}
+	multiply := func(l, r *C.VipsImage) *C.VipsImage {
+		return s.do(func() (*C.VipsImage, error) { return vipsGenMultiply(l, r) })
+	}
+	subtract := func(l, r *C.VipsImage) *C.VipsImage {
+		return s.do(func() (*C.VipsImage, error) { return vipsGenSubtract(l, r) })
+	}
+	add := func(l, r *C.VipsImage) *C.VipsImage {
+		return s.do(func() (*C.VipsImage, error) { return vipsGenAdd(l, r) })
+	}
+	linear := func(in *C.VipsImage, a, b float64) *C.VipsImage {
+		return s.do(func() (*C.VipsImage, error) {
+			return vipsGenLinear(in, []float64{a}, []float64{b}, nil)
+		})
+	}
+
+	muX, muY := blur(x), blur(y)
+	muX2, muY2, muXY := multiply(muX, muX), multiply(muY, muY), multiply(muX, muY)
+
+	sigmaX2 := subtract(blur(multiply(x, x)), muX2)
+	sigmaY2 := subtract(blur(multiply(y, y)), muY2)
+	sigmaXY := subtract(blur(multiply(x, y)), muXY)
+
+	numerator := multiply(linear(muXY, 2, c1), linear(sigmaXY, 2, c2))
+	denominator := multiply(linear(add(muX2, muY2), 1, c1), linear(add(sigmaX2, sigmaY2), 1, c2))
+	return s.do(func() (*C.VipsImage, error) { return vipsGenDivide(numerator, denominator) })
+}
+
+// imageScratch runs a chain of operations, keeping the intermediate images until clear is
+// called. After the first error the remaining operations are skipped.
+type imageScratch struct {
+	images []*C.VipsImage
+	err    error
+}
+
+func (s *imageScratch) do(fn func() (*C.VipsImage, error)) *C.VipsImage {
+	if s.err != nil {
+		return nil
+	}
+	out, err := fn()
+	if err != nil {
+		s.err = err
+		return nil
+	}
+	s.images = append(s.images, out)
+	return out
+}
+
+func (s *imageScratch) clear() {
+	for _, img := range s.images {
+		clearImage(img)
+	}
+	s.images = nil
+}
+
+func psnr(mse, peak float64) float64 {
+	if mse == 0 {
+		return math.Inf(1)
+	}
+	return 10 * math.Log10(peak*peak/mse)
+}
+
+// formatPeak returns the largest pixel value usually found in images of format
+func formatPeak(format BandFormat) float64 {
+	switch format {
+	case BandFormatUchar, BandFormatChar:
+		return 255
+	case BandFormatUshort, BandFormatShort:
+		return 65535
+	case BandFormatUint, BandFormatInt:
+		return math.MaxUint32
+	default:
+		return 1
+	}
+}
@@ -0,0 +1,119 @@
+package vips
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompare_Identical(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	img, err := NewImageFromFile(resources + "png-24bit.png")
+	require.NoError(t, err)
+	defer img.Close()
+
+	mse, err := Compare(img, img, MetricMSE)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, mse)
+
+	psnr, err := Compare(img, img, MetricPSNR)
+	require.NoError(t, err)
+	assert.True(t, math.IsInf(psnr, 1))
+
+	ssim, err := Compare(img, img, MetricSSIM)
+	require.NoError(t, err)
+	assert.InDelta(t, 1, ssim, 1e-6)
+}
+
+func TestCompare_Blurred(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	original, err := NewImageFromFile(resources + "png-24bit.png")
+	require.NoError(t, err)
+	defer original.Close()
+
+	slightly, err := original.Copy()
+	require.NoError(t, err)
+	defer slightly.Close()
+	require.NoError(t, slightly.GaussianBlur(0.5))
+
+	heavily, err := original.Copy()
+	require.NoError(t, err)
+	defer heavily.Close()
+	require.NoError(t, heavily.GaussianBlur(4))
+
+	for _, metric := range []Metric{MetricMSE, MetricPSNR, MetricSSIM} {
+		slight, err := Compare(original, slightly, metric)
+		require.NoError(t, err)
+		heavy, err := Compare(original, heavily, metric)
+		require.NoError(t, err)
+
+		if metric == MetricMSE {
+			assert.Less(t, slight, heavy)
+		} else {
+			assert.Greater(t, slight, heavy)
+		}
+	}
+
+	ssim, err := Compare(original, heavily, MetricSSIM)
+	require.NoError(t, err)
+	assert.Greater(t, ssim, 0.0)
+	assert.Less(t, ssim, 1.0)
+}
+
+func TestCompareBands(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	original, err := NewImageFromFile(resources + "png-24bit.png")
+	require.NoError(t, err)
+	defer original.Close()
+
+	// Only change the first band
+	shifted, err := original.Copy()
+	require.NoError(t, err)
+	defer shifted.Close()
+	require.NoError(t, shifted.Linear([]float64{0.5, 1, 1}, []float64{0, 0, 0}))
+	require.NoError(t, shifted.Cast(BandFormatUchar))
+
+	mse, err := CompareBands(original, shifted, MetricMSE)
+	require.NoError(t, err)
+	require.Len(t, mse, original.Bands())
+	assert.Greater(t, mse[0], 0.0)
+	assert.Equal(t, 0.0, mse[1])
+	assert.Equal(t, 0.0, mse[2])
+
+	total, err := Compare(original, shifted, MetricMSE)
+	require.NoError(t, err)
+	assert.InDelta(t, mse[0]/3, total, 1e-6)
+}
+
+func TestCompare_Mismatch(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	img, err := NewImageFromFile(resources + "png-24bit.png")
+	require.NoError(t, err)
+	defer img.Close()
+
+	other, err := Black(10, 10)
+	require.NoError(t, err)
+	defer other.Close()
+
+	_, err = Compare(img, other, MetricSSIM)
+	assert.Error(t, err)
+}
+
+func TestCompare_SSIMWindow(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	// gaussblur builds its mask like gaussmat, which must match the 11 tap window of SSIM
+	separable := true
+	mask, err := vipsGenGaussmat(ssimSigma, ssimMinAmpl, &GaussmatOptions{Separable: &separable})
+	require.NoError(t, err)
+	img := newImageRef(mask, ImageTypeUnknown, ImageTypeUnknown, nil)
+	defer img.Close()
+
+	assert.Equal(t, 11, img.Width())
+}
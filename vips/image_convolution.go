@@ -0,0 +1,147 @@
+package vips
+
+// #include "image.h"
+import "C"
+
+import (
+	"errors"
+	"runtime"
+)
+
+// Kernel2D is a convolution kernel. Rows holds the coefficients row by row; each
+// output pixel is the sum of the coefficients times the pixels under them, divided
+// by Scale and plus Offset. A zero Scale is treated as 1.
+type Kernel2D struct {
+	Rows   [][]float64
+	Scale  float64
+	Offset float64
+}
+
+// NewKernel2D creates a kernel from rows of equal length, with Scale set to the sum of
+// the coefficients so that the kernel preserves brightness, or to 1 if they sum to zero.
+func NewKernel2D(rows [][]float64) (*Kernel2D, error) {
+	k := &Kernel2D{Rows: rows}
+	if _, _, err := k.values(); err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		for _, v := range row {
+			k.Scale += v
+		}
+	}
+	if k.Scale == 0 {
+		k.Scale = 1
+	}
+	return k, nil
+}
+
+// Width returns the number of columns in the kernel
+func (k *Kernel2D) Width() int {
+	if len(k.Rows) == 0 {
+		return 0
+	}
+	return len(k.Rows[0])
+}
+
+// Height returns the number of rows in the kernel
+func (k *Kernel2D) Height() int {
+	return len(k.Rows)
+}
+
+func (k *Kernel2D) values() (int, []float64, error) {
+	if k.Height() == 0 || k.Width() == 0 {
+		return 0, nil, errors.New("kernel must not be empty")
+	}
+
+	values := make([]float64, 0, k.Width()*k.Height())
+	for _, row := range k.Rows {
+		if len(row) != k.Width() {
+			return 0, nil, errors.New("kernel rows must have equal length")
+		}
+		values = append(values, row...)
+	}
+	return k.Width(), values, nil
+}
+
+// matrixImage converts the kernel to a libvips matrix image carrying its scale and offset
+func (k *Kernel2D) matrixImage() (*C.VipsImage, error) {
+	width, values, err := k.values()
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := vipsMatrixImage(width, k.Height(), values)
+	if err != nil {
+		return nil, err
+	}
+
+	scale := k.Scale
+	if scale == 0 {
+		scale = 1
+	}
+	vipsImageSetDouble(out, "scale", scale)
+	vipsImageSetDouble(out, "offset", k.Offset)
+	return out, nil
+}
+
+// Convolve convolves the image with kernel k. PrecisionInteger is fastest and rounds the
+// kernel to integers, PrecisionFloat is exact and PrecisionApproximate is a fast
+// approximation for large kernels.
+func (r *ImageRef) Convolve(k *Kernel2D, precision Precision) error {
+	defer runtime.KeepAlive(r)
+	mask, err := k.matrixImage()
+	if err != nil {
+		return err
+	}
+	defer clearImage(mask)
+
+	out, err := vipsGenConv(r.image, mask, &ConvOptions{Precision: &precision})
+	if err != nil {
+		return err
+	}
+	r.setImage(out)
+	return nil
+}
+
+// ConvolveSeparable convolves the image with a one row kernel, first horizontally and then
+// vertically with its transpose. This is much faster than Convolve for kernels such as a
+// Gaussian which can be separated.
+func (r *ImageRef) ConvolveSeparable(k *Kernel2D, precision Precision) error {
+	defer runtime.KeepAlive(r)
+	if k.Height() != 1 {
+		return errors.New("separable kernel must have a single row")
+	}
+
+	mask, err := k.matrixImage()
+	if err != nil {
+		return err
+	}
+	defer clearImage(mask)
+
+	out, err := vipsGenConvsep(r.image, mask, &ConvsepOptions{Precision: &precision})
+	if err != nil {
+		return err
+	}
+	r.setImage(out)
+	return nil
+}
+
+// Compass convolves the image with kernel k, then with k rotated by angle, repeated times
+// in total, and combines the results with combine. For example an edge kernel applied 4
+// times at Angle45_90 with CombineMax detects edges in every direction.
+func (r *ImageRef) Compass(k *Kernel2D, times int, angle Angle45, combine Combine) error {
+	defer runtime.KeepAlive(r)
+	mask, err := k.matrixImage()
+	if err != nil {
+		return err
+	}
+	defer clearImage(mask)
+
+	out, err := vipsGenCompass(r.image, mask, &CompassOptions{Times: &times, Angle: &angle, Combine: &combine})
+	if err != nil {
+		return err
+	}
+	r.setImage(out)
+	return nil
+}
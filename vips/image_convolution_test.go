@@ -0,0 +1,102 @@
+package vips
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewKernel2D(t *testing.T) {
+	k, err := NewKernel2D([][]float64{
+		{1, 2, 1},
+		{2, 4, 2},
+		{1, 2, 1},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, k.Width())
+	assert.Equal(t, 3, k.Height())
+	assert.Equal(t, 16.0, k.Scale)
+	assert.Equal(t, 0.0, k.Offset)
+
+	edge, err := NewKernel2D([][]float64{{-1, 0, 1}})
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, edge.Scale)
+
+	_, err = NewKernel2D([][]float64{{1, 1}, {1}})
+	assert.Error(t, err)
+
+	_, err = NewKernel2D(nil)
+	assert.Error(t, err)
+}
+
+func TestImageRef_Convolve_Identity(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	original, err := NewImageFromFile(resources + "png-24bit.png")
+	require.NoError(t, err)
+	defer original.Close()
+
+	img, err := original.Copy()
+	require.NoError(t, err)
+	defer img.Close()
+
+	k, err := NewKernel2D([][]float64{
+		{0, 0, 0},
+		{0, 1, 0},
+		{0, 0, 0},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, img.Convolve(k, PrecisionInteger))
+	assert.Equal(t, 0.0, maxAbsDifference(t, original, img))
+}
+
+func TestImageRef_ConvolveSeparable(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	full, err := NewImageFromFile(resources + "png-24bit.png")
+	require.NoError(t, err)
+	defer full.Close()
+
+	separable, err := full.Copy()
+	require.NoError(t, err)
+	defer separable.Close()
+
+	box, err := NewKernel2D([][]float64{
+		{1, 1, 1},
+		{1, 1, 1},
+		{1, 1, 1},
+	})
+	require.NoError(t, err)
+	require.NoError(t, full.Convolve(box, PrecisionFloat))
+
+	row, err := NewKernel2D([][]float64{{1, 1, 1}})
+	require.NoError(t, err)
+	require.NoError(t, separable.ConvolveSeparable(row, PrecisionFloat))
+
+	assert.InDelta(t, 0, maxAbsDifference(t, full, separable), 1)
+
+	assert.Error(t, separable.ConvolveSeparable(box, PrecisionFloat))
+}
+
+func TestImageRef_Compass(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	img, err := NewImageFromFile(resources + "png-24bit.png")
+	require.NoError(t, err)
+	defer img.Close()
+
+	width, height := img.Width(), img.Height()
+
+	k, err := NewKernel2D([][]float64{
+		{-1, 0, 1},
+		{-2, 0, 2},
+		{-1, 0, 1},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, img.Compass(k, 4, Angle45_90, CombineMax))
+	assert.Equal(t, width, img.Width())
+	assert.Equal(t, height, img.Height())
+}
@@ -0,0 +1,282 @@
+package vips
+
+// #include "dzsave.h"
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"unsafe"
+)
+
+// DzLayout is the directory layout of a tile pyramid written by ExportDeepZoom
+type DzLayout int
+
+// DzLayout enum
+const (
+	// DzLayoutDeepZoom writes a Microsoft Deep Zoom pyramid, as read by OpenSeadragon
+	DzLayoutDeepZoom DzLayout = C.VIPS_FOREIGN_DZ_LAYOUT_DZ
+	// DzLayoutZoomify writes a Zoomify pyramid
+	DzLayoutZoomify DzLayout = C.VIPS_FOREIGN_DZ_LAYOUT_ZOOMIFY
+	// DzLayoutGoogle writes a Google Maps style z/y/x pyramid
+	DzLayoutGoogle DzLayout = C.VIPS_FOREIGN_DZ_LAYOUT_GOOGLE
+	// DzLayoutIIIF writes a IIIF Image API 2 level 0 pyramid
+	DzLayoutIIIF DzLayout = C.VIPS_FOREIGN_DZ_LAYOUT_IIIF
+	// DzLayoutIIIF3 writes a IIIF Image API 3 level 0 pyramid
+	DzLayoutIIIF3 DzLayout = C.VIPS_FOREIGN_DZ_LAYOUT_IIIF3
+)
+
+// DzDepth controls how deep a tile pyramid written by ExportDeepZoom goes
+type DzDepth int
+
+// DzDepth enum
+const (
+	// DzDepthDefault uses the libvips default for the layout
+	DzDepthDefault DzDepth = iota
+	// DzDepthOnePixel shrinks until the image fits in a single pixel
+	DzDepthOnePixel
+	// DzDepthOneTile shrinks until the image fits in a single tile
+	DzDepthOneTile
+	// DzDepthOne writes only the full size layer
+	DzDepthOne
+)
+
+// DefaultDzName is the base name of the pyramid when DzExportParams.Name is empty
+const DefaultDzName = "image"
+
+// DzExportParams are options when exporting a tile pyramid with ExportDeepZoom
+type DzExportParams struct {
+	// Name is the base name of the pyramid, e.g. "plan" gives plan.dzi and plan_files for
+	// DzLayoutDeepZoom. Defaults to DefaultDzName.
+	Name   string
+	Layout DzLayout
+	// TileSize is the width and height of the tiles. Zero uses the layout default: 254 for
+	// DzLayoutDeepZoom and 256 otherwise.
+	TileSize int
+	// Overlap is the number of pixels shared by neighbouring tiles. A negative value uses the
+	// layout default: 1 for DzLayoutDeepZoom and 0 otherwise.
+	Overlap int
+	Depth   DzDepth
+	// Format is the tile format: ImageTypeJPEG, ImageTypePNG or ImageTypeWEBP
+	Format ImageType
+	// Quality is used by lossy tile formats
+	Quality int
+	// Suffix overrides Format and Quality with a libvips save suffix, e.g. ".webp[Q=80,lossless]"
+	Suffix string
+	// Background fills the edges of the tiles. Defaults to black.
+	Background *ColorRGBA
+	// Centre centres the image within the tiles, as some Google Maps viewers expect
+	Centre bool
+	// ID is the base URI written to the info.json of IIIF layouts
+	ID string
+	// SkipBlanks skips tiles whose pixels all differ from the background by no more than
+	// SkipBlanksThreshold
+	SkipBlanks          bool
+	SkipBlanksThreshold int
+}
+
+// NewDzExportParams creates default values for a Deep Zoom export with JPEG tiles
+func NewDzExportParams() *DzExportParams {
+	return &DzExportParams{
+		Layout:  DzLayoutDeepZoom,
+		Overlap: -1,
+		Format:  ImageTypeJPEG,
+		Quality: 75,
+	}
+}
+
+func (p *DzExportParams) name() string {
+	if p.Name == "" {
+		return DefaultDzName
+	}
+	return p.Name
+}
+
+func (p *DzExportParams) suffix() (string, error) {
+	if p.Suffix != "" {
+		return p.Suffix, nil
+	}
+
+	var ext string
+	switch p.Format {
+	case ImageTypeJPEG, ImageTypeUnknown:
+		ext = ".jpeg"
+	case ImageTypePNG:
+		return ".png", nil
+	case ImageTypeWEBP:
+		ext = ".webp"
+	default:
+		return "", fmt.Errorf("unsupported tile format: %v", ImageTypes[p.Format])
+	}
+
+	if p.Quality > 0 {
+		return fmt.Sprintf("%s[Q=%d]", ext, p.Quality), nil
+	}
+	return ext, nil
+}
+
+// ExportDeepZoom writes the image as a tile pyramid into dir, which is created if needed.
+// The files written depend on the layout, e.g. <name>.dzi and <name>_files for Deep Zoom,
+// or a <name> directory for the other layouts.
+func (r *ImageRef) ExportDeepZoom(dir string, params *DzExportParams) error {
+	defer runtime.KeepAlive(r)
+	if params == nil {
+		params = NewDzExportParams()
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	return r.evaluate(func() error {
+		return vipsDzSaveToFile(r.image, filepath.Join(dir, params.name()), *params)
+	})
+}
+
+// ExportDeepZoomZip exports the image as a tile pyramid stored in a zip archive. All
+// entries are below a top level directory named after the pyramid.
+func (r *ImageRef) ExportDeepZoomZip(params *DzExportParams) ([]byte, error) {
+	defer runtime.KeepAlive(r)
+	if params == nil {
+		params = NewDzExportParams()
+	}
+
+	var buf []byte
+	err := r.evaluate(func() (err error) {
+		buf, err = vipsDzSaveToZipBuffer(r.image, *params)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// ExportDeepZoomTiles writes the image as a tile pyramid into a temporary directory, then
+// calls fn with the slash separated path and contents of every file of the pyramid, including
+// the tiles and the layout's metadata files. Paths are relative to the directory, as written by
+// ExportDeepZoom. Only one file is held in memory at a time and the directory is removed
+// before returning. Iteration stops at the first error returned by fn.
+func (r *ImageRef) ExportDeepZoomTiles(params *DzExportParams, fn func(name string, data []byte) error) error {
+	dir, err := os.MkdirTemp("", "govips-dz-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	if err := r.ExportDeepZoom(dir, params); err != nil {
+		return err
+	}
+
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return fn(filepath.ToSlash(name), data)
+	})
+}
+
+func createDzSaveParams(in *C.VipsImage, params DzExportParams) (C.struct_DzSaveParams, error) {
+	suffix, err := params.suffix()
+	if err != nil {
+		return C.struct_DzSaveParams{}, err
+	}
+
+	p := C.struct_DzSaveParams{
+		inputImage: in,
+		layout:     C.VipsForeignDzLayout(params.Layout),
+		tileSize:   C.int(params.TileSize),
+		overlap:    C.int(params.Overlap),
+		depth:      -1,
+		centre:     C.int(boolToInt(params.Centre)),
+		skipBlanks: C.int(boolToInt(params.SkipBlanks)),
+
+		skipBlanksThreshold: C.int(params.SkipBlanksThreshold),
+	}
+
+	switch params.Depth {
+	case DzDepthDefault:
+	case DzDepthOnePixel:
+		p.depth = C.VIPS_FOREIGN_DZ_DEPTH_ONEPIXEL
+	case DzDepthOneTile:
+		p.depth = C.VIPS_FOREIGN_DZ_DEPTH_ONETILE
+	case DzDepthOne:
+		p.depth = C.VIPS_FOREIGN_DZ_DEPTH_ONE
+	default:
+		return C.struct_DzSaveParams{}, errors.New("unknown pyramid depth")
+	}
+
+	p.basename = C.CString(params.name())
+	p.suffix = C.CString(suffix)
+	if params.ID != "" {
+		p.id = C.CString(params.ID)
+	}
+
+	if background := vipsBackground(in, params.Background); len(background) > 0 {
+		p.background = (*C.double)(C.malloc(C.size_t(len(background)) * C.sizeof_double))
+		copy(unsafe.Slice((*float64)(unsafe.Pointer(p.background)), len(background)), background)
+		p.backgroundLen = C.int(len(background))
+	}
+	return p, nil
+}
+
+func freeDzSaveParams(p C.struct_DzSaveParams) {
+	freeCString(p.basename)
+	freeCString(p.suffix)
+	if p.id != nil {
+		freeCString(p.id)
+	}
+	if p.background != nil {
+		C.free(unsafe.Pointer(p.background))
+	}
+}
+
+// https://www.libvips.org/API/current/VipsForeignSave.html#vips-dzsave
+func vipsDzSaveToFile(in *C.VipsImage, path string, params DzExportParams) error {
+	incOpCounter("save_dz")
+	p, err := createDzSaveParams(in, params)
+	if err != nil {
+		return err
+	}
+	defer freeDzSaveParams(p)
+
+	cPath := C.CString(path)
+	defer freeCString(cPath)
+
+	if err := C.dzsave_to_file(cPath, &p); err != 0 {
+		return handleVipsError()
+	}
+	return nil
+}
+
+// https://www.libvips.org/API/current/VipsForeignSave.html#vips-dzsave-buffer
+func vipsDzSaveToZipBuffer(in *C.VipsImage, params DzExportParams) ([]byte, error) {
+	incOpCounter("save_dz_buffer")
+	p, err := createDzSaveParams(in, params)
+	if err != nil {
+		return nil, err
+	}
+	defer freeDzSaveParams(p)
+
+	if err := C.dzsave_to_zip_buffer(&p); err != 0 {
+		return nil, handleSaveBufferError(p.outputBuffer)
+	}
+
+	buf := C.GoBytes(p.outputBuffer, C.int(p.outputLen))
+	defer gFreePointer(p.outputBuffer)
+
+	return buf, nil
+}
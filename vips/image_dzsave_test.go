@@ -0,0 +1,134 @@
+package vips
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageRef_ExportDeepZoom(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	img, err := NewImageFromFile(resources + "png-24bit.png")
+	require.NoError(t, err)
+	defer img.Close()
+
+	dir := t.TempDir()
+	params := NewDzExportParams()
+	params.Name = "plan"
+	require.NoError(t, img.ExportDeepZoom(dir, params))
+
+	dzi, err := os.ReadFile(filepath.Join(dir, "plan.dzi"))
+	require.NoError(t, err)
+	assert.Contains(t, string(dzi), `TileSize="254"`)
+	assert.Contains(t, string(dzi), `Overlap="1"`)
+	assert.Contains(t, string(dzi), `Format="jpeg"`)
+
+	// 1920x1080 needs 12 levels to shrink to one pixel, so the full size layer is 11
+	_, err = os.Stat(filepath.Join(dir, "plan_files", "11", "0_0.jpeg"))
+	assert.NoError(t, err)
+	_, err = os.Stat(filepath.Join(dir, "plan_files", "0", "0_0.jpeg"))
+	assert.NoError(t, err)
+}
+
+func TestImageRef_ExportDeepZoom_Google(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	img, err := NewImageFromFile(resources + "png-24bit.png")
+	require.NoError(t, err)
+	defer img.Close()
+
+	dir := t.TempDir()
+	params := NewDzExportParams()
+	params.Layout = DzLayoutGoogle
+	params.TileSize = 512
+	params.Format = ImageTypePNG
+	params.Background = &ColorRGBA{R: 255, G: 255, B: 255, A: 255}
+	require.NoError(t, img.ExportDeepZoom(dir, params))
+
+	tile, err := NewImageFromFile(filepath.Join(dir, DefaultDzName, "0", "0", "0.png"))
+	require.NoError(t, err)
+	defer tile.Close()
+	assert.Equal(t, 512, tile.Width())
+	assert.Equal(t, 512, tile.Height())
+}
+
+func TestImageRef_ExportDeepZoomZip(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	img, err := NewImageFromFile(resources + "png-24bit.png")
+	require.NoError(t, err)
+	defer img.Close()
+
+	params := NewDzExportParams()
+	params.Depth = DzDepthOne
+	params.Format = ImageTypeWEBP
+	buf, err := img.ExportDeepZoomZip(params)
+	require.NoError(t, err)
+
+	archive, err := zip.NewReader(bytes.NewReader(buf), int64(len(buf)))
+	require.NoError(t, err)
+
+	var names []string
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+	}
+	assert.Contains(t, names, "image/image.dzi")
+	assert.Contains(t, names, "image/image_files/0/0_0.webp")
+}
+
+func TestImageRef_ExportDeepZoomTiles(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	img, err := NewImageFromFile(resources + "png-24bit.png")
+	require.NoError(t, err)
+	defer img.Close()
+
+	params := NewDzExportParams()
+	params.Layout = DzLayoutIIIF3
+	params.ID = "https://example.com/iiif"
+
+	var tiles int
+	var info []byte
+	err = img.ExportDeepZoomTiles(params, func(name string, data []byte) error {
+		switch {
+		case name == DefaultDzName+"/info.json":
+			info = data
+		case strings.HasSuffix(name, ".jpg"), strings.HasSuffix(name, ".jpeg"):
+			tiles++
+			assert.Equal(t, ImageTypeJPEG, DetermineImageType(data))
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Greater(t, tiles, 1)
+	assert.Contains(t, string(info), "https://example.com/iiif")
+
+	stop := errors.New("stop")
+	var calls int
+	err = img.ExportDeepZoomTiles(params, func(name string, data []byte) error {
+		calls++
+		return stop
+	})
+	assert.True(t, errors.Is(err, stop))
+	assert.Equal(t, 1, calls)
+}
+
+func TestImageRef_ExportDeepZoom_UnsupportedFormat(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	img, err := NewImageFromFile(resources + "png-24bit.png")
+	require.NoError(t, err)
+	defer img.Close()
+
+	params := NewDzExportParams()
+	params.Format = ImageTypeSVG
+	assert.Error(t, img.ExportDeepZoom(t.TempDir(), params))
+}
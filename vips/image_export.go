@@ -5,16 +5,18 @@ import "C"
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"image"
+	"io"
 	"runtime"
 	"unsafe"
 )
 
 // Export creates a byte array of the image for use.
-// The function returns a byte array that can be written to a file e.g. via os.WriteFile().
-// N.B. govips does not currently have built-in support for directly exporting to a file.
+// The function returns a byte array that can be written to a file e.g. via os.WriteFile(),
+// although SaveToFile writes to disk without holding the encoded image in memory.
 // The function also returns a copy of the image metadata as well as an error.
 // Deprecated: Use ExportNative or format-specific Export methods
 func (r *ImageRef) Export(params *ExportParams) ([]byte, *ImageMetadata, error) {
@@ -25,7 +27,7 @@ func (r *ImageRef) Export(params *ExportParams) ([]byte, *ImageMetadata, error)
 	format := params.Format
 
 	if !IsTypeSupported(format) {
-		return nil, r.newMetadata(ImageTypeUnknown), fmt.Errorf("cannot save to %#v", ImageTypes[format])
+		return nil, r.newMetadata(ImageTypeUnknown), fmt.Errorf("cannot save to %#v: %w", ImageTypes[format], ErrUnsupportedSaveFormat)
 	}
 
 	switch format {
@@ -92,38 +94,52 @@ func (r *ImageRef) Export(params *ExportParams) ([]byte, *ImageMetadata, error)
 
 // ExportNative exports the image to a buffer based on its native format with default parameters.
 func (r *ImageRef) ExportNative() ([]byte, *ImageMetadata, error) {
+	return r.ExportNativeContext(context.Background())
+}
+
+// ExportNativeContext exports the image like ExportNative, aborting the export with ctx.Err() once ctx is done.
+func (r *ImageRef) ExportNativeContext(ctx context.Context) ([]byte, *ImageMetadata, error) {
 	switch r.format {
 	case ImageTypeJPEG:
-		return r.ExportJpeg(NewJpegExportParams())
+		return r.ExportJpegContext(ctx, NewJpegExportParams())
 	case ImageTypePNG:
-		return r.ExportPng(NewPngExportParams())
+		return r.ExportPngContext(ctx, NewPngExportParams())
 	case ImageTypeWEBP:
-		return r.ExportWebp(NewWebpExportParams())
+		return r.ExportWebpContext(ctx, NewWebpExportParams())
 	case ImageTypeHEIF:
-		return r.ExportHeif(NewHeifExportParams())
+		return r.ExportHeifContext(ctx, NewHeifExportParams())
 	case ImageTypeTIFF:
-		return r.ExportTiff(NewTiffExportParams())
+		return r.ExportTiffContext(ctx, NewTiffExportParams())
 	case ImageTypeAVIF:
-		return r.ExportAvif(NewAvifExportParams())
+		return r.ExportAvifContext(ctx, NewAvifExportParams())
 	case ImageTypeJP2K:
-		return r.ExportJp2k(NewJp2kExportParams())
+		return r.ExportJp2kContext(ctx, NewJp2kExportParams())
 	case ImageTypeGIF:
-		return r.ExportGIF(NewGifExportParams())
+		return r.ExportGIFContext(ctx, NewGifExportParams())
 	case ImageTypeJXL:
-		return r.ExportJxl(NewJxlExportParams())
+		return r.ExportJxlContext(ctx, NewJxlExportParams())
 	default:
-		return r.ExportJpeg(NewJpegExportParams())
+		return r.ExportJpegContext(ctx, NewJpegExportParams())
 	}
 }
 
 // ExportJpeg exports the image as JPEG to a buffer.
 func (r *ImageRef) ExportJpeg(params *JpegExportParams) ([]byte, *ImageMetadata, error) {
+	return r.ExportJpegContext(context.Background(), params)
+}
+
+// ExportJpegContext exports the image as JPEG to a buffer, aborting the export with ctx.Err() once ctx is done.
+func (r *ImageRef) ExportJpegContext(ctx context.Context, params *JpegExportParams) ([]byte, *ImageMetadata, error) {
 	defer runtime.KeepAlive(r)
 	if params == nil {
 		params = NewJpegExportParams()
 	}
 
-	buf, err := vipsSaveJPEGToBuffer(r.image, *params)
+	var buf []byte
+	err := vipsEvaluate(ctx, r.image, r.progress, func() (err error) {
+		buf, err = vipsSaveJPEGToBuffer(r.image, *params)
+		return err
+	})
 	if err != nil {
 		return nil, nil, err
 	}
@@ -133,12 +149,21 @@ func (r *ImageRef) ExportJpeg(params *JpegExportParams) ([]byte, *ImageMetadata,
 
 // ExportPng exports the image as PNG to a buffer.
 func (r *ImageRef) ExportPng(params *PngExportParams) ([]byte, *ImageMetadata, error) {
+	return r.ExportPngContext(context.Background(), params)
+}
+
+// ExportPngContext exports the image as PNG to a buffer, aborting the export with ctx.Err() once ctx is done.
+func (r *ImageRef) ExportPngContext(ctx context.Context, params *PngExportParams) ([]byte, *ImageMetadata, error) {
 	defer runtime.KeepAlive(r)
 	if params == nil {
 		params = NewPngExportParams()
 	}
 
-	buf, err := vipsSavePNGToBuffer(r.image, *params)
+	var buf []byte
+	err := vipsEvaluate(ctx, r.image, r.progress, func() (err error) {
+		buf, err = vipsSavePNGToBuffer(r.image, *params)
+		return err
+	})
 	if err != nil {
 		return nil, nil, err
 	}
@@ -148,6 +173,11 @@ func (r *ImageRef) ExportPng(params *PngExportParams) ([]byte, *ImageMetadata, e
 
 // ExportWebp exports the image as WEBP to a buffer.
 func (r *ImageRef) ExportWebp(params *WebpExportParams) ([]byte, *ImageMetadata, error) {
+	return r.ExportWebpContext(context.Background(), params)
+}
+
+// ExportWebpContext exports the image as WEBP to a buffer, aborting the export with ctx.Err() once ctx is done.
+func (r *ImageRef) ExportWebpContext(ctx context.Context, params *WebpExportParams) ([]byte, *ImageMetadata, error) {
 	defer runtime.KeepAlive(r)
 	if params == nil {
 		params = NewWebpExportParams()
@@ -156,7 +186,11 @@ func (r *ImageRef) ExportWebp(params *WebpExportParams) ([]byte, *ImageMetadata,
 	paramsWithIccProfile := *params
 	paramsWithIccProfile.IccProfile = r.optimizedIccProfile
 
-	buf, err := vipsSaveWebPToBuffer(r.image, paramsWithIccProfile)
+	var buf []byte
+	err := vipsEvaluate(ctx, r.image, r.progress, func() (err error) {
+		buf, err = vipsSaveWebPToBuffer(r.image, paramsWithIccProfile)
+		return err
+	})
 	if err != nil {
 		return nil, nil, err
 	}
@@ -166,12 +200,21 @@ func (r *ImageRef) ExportWebp(params *WebpExportParams) ([]byte, *ImageMetadata,
 
 // ExportHeif exports the image as HEIF to a buffer.
 func (r *ImageRef) ExportHeif(params *HeifExportParams) ([]byte, *ImageMetadata, error) {
+	return r.ExportHeifContext(context.Background(), params)
+}
+
+// ExportHeifContext exports the image as HEIF to a buffer, aborting the export with ctx.Err() once ctx is done.
+func (r *ImageRef) ExportHeifContext(ctx context.Context, params *HeifExportParams) ([]byte, *ImageMetadata, error) {
 	defer runtime.KeepAlive(r)
 	if params == nil {
 		params = NewHeifExportParams()
 	}
 
-	buf, err := vipsSaveHEIFToBuffer(r.image, *params)
+	var buf []byte
+	err := vipsEvaluate(ctx, r.image, r.progress, func() (err error) {
+		buf, err = vipsSaveHEIFToBuffer(r.image, *params)
+		return err
+	})
 	if err != nil {
 		return nil, nil, err
 	}
@@ -181,12 +224,21 @@ func (r *ImageRef) ExportHeif(params *HeifExportParams) ([]byte, *ImageMetadata,
 
 // ExportTiff exports the image as TIFF to a buffer.
 func (r *ImageRef) ExportTiff(params *TiffExportParams) ([]byte, *ImageMetadata, error) {
+	return r.ExportTiffContext(context.Background(), params)
+}
+
+// ExportTiffContext exports the image as TIFF to a buffer, aborting the export with ctx.Err() once ctx is done.
+func (r *ImageRef) ExportTiffContext(ctx context.Context, params *TiffExportParams) ([]byte, *ImageMetadata, error) {
 	defer runtime.KeepAlive(r)
 	if params == nil {
 		params = NewTiffExportParams()
 	}
 
-	buf, err := vipsSaveTIFFToBuffer(r.image, *params)
+	var buf []byte
+	err := vipsEvaluate(ctx, r.image, r.progress, func() (err error) {
+		buf, err = vipsSaveTIFFToBuffer(r.image, *params)
+		return err
+	})
 	if err != nil {
 		return nil, nil, err
 	}
@@ -196,12 +248,21 @@ func (r *ImageRef) ExportTiff(params *TiffExportParams) ([]byte, *ImageMetadata,
 
 // ExportGIF exports the image as GIF to a buffer.
 func (r *ImageRef) ExportGIF(params *GifExportParams) ([]byte, *ImageMetadata, error) {
+	return r.ExportGIFContext(context.Background(), params)
+}
+
+// ExportGIFContext exports the image as GIF to a buffer, aborting the export with ctx.Err() once ctx is done.
+func (r *ImageRef) ExportGIFContext(ctx context.Context, params *GifExportParams) ([]byte, *ImageMetadata, error) {
 	defer runtime.KeepAlive(r)
 	if params == nil {
 		params = NewGifExportParams()
 	}
 
-	buf, err := vipsSaveGIFToBuffer(r.image, *params)
+	var buf []byte
+	err := vipsEvaluate(ctx, r.image, r.progress, func() (err error) {
+		buf, err = vipsSaveGIFToBuffer(r.image, *params)
+		return err
+	})
 	if err != nil {
 		return nil, nil, err
 	}
@@ -211,12 +272,21 @@ func (r *ImageRef) ExportGIF(params *GifExportParams) ([]byte, *ImageMetadata, e
 
 // ExportAvif exports the image as AVIF to a buffer.
 func (r *ImageRef) ExportAvif(params *AvifExportParams) ([]byte, *ImageMetadata, error) {
+	return r.ExportAvifContext(context.Background(), params)
+}
+
+// ExportAvifContext exports the image as AVIF to a buffer, aborting the export with ctx.Err() once ctx is done.
+func (r *ImageRef) ExportAvifContext(ctx context.Context, params *AvifExportParams) ([]byte, *ImageMetadata, error) {
 	defer runtime.KeepAlive(r)
 	if params == nil {
 		params = NewAvifExportParams()
 	}
 
-	buf, err := vipsSaveAVIFToBuffer(r.image, *params)
+	var buf []byte
+	err := vipsEvaluate(ctx, r.image, r.progress, func() (err error) {
+		buf, err = vipsSaveAVIFToBuffer(r.image, *params)
+		return err
+	})
 	if err != nil {
 		return nil, nil, err
 	}
@@ -226,12 +296,21 @@ func (r *ImageRef) ExportAvif(params *AvifExportParams) ([]byte, *ImageMetadata,
 
 // ExportJp2k exports the image as JPEG2000 to a buffer.
 func (r *ImageRef) ExportJp2k(params *Jp2kExportParams) ([]byte, *ImageMetadata, error) {
+	return r.ExportJp2kContext(context.Background(), params)
+}
+
+// ExportJp2kContext exports the image as JPEG2000 to a buffer, aborting the export with ctx.Err() once ctx is done.
+func (r *ImageRef) ExportJp2kContext(ctx context.Context, params *Jp2kExportParams) ([]byte, *ImageMetadata, error) {
 	defer runtime.KeepAlive(r)
 	if params == nil {
 		params = NewJp2kExportParams()
 	}
 
-	buf, err := vipsSaveJP2KToBuffer(r.image, *params)
+	var buf []byte
+	err := vipsEvaluate(ctx, r.image, r.progress, func() (err error) {
+		buf, err = vipsSaveJP2KToBuffer(r.image, *params)
+		return err
+	})
 	if err != nil {
 		return nil, nil, err
 	}
@@ -241,12 +320,21 @@ func (r *ImageRef) ExportJp2k(params *Jp2kExportParams) ([]byte, *ImageMetadata,
 
 // ExportJxl exports the image as JPEG XL to a buffer.
 func (r *ImageRef) ExportJxl(params *JxlExportParams) ([]byte, *ImageMetadata, error) {
+	return r.ExportJxlContext(context.Background(), params)
+}
+
+// ExportJxlContext exports the image as JPEG XL to a buffer, aborting the export with ctx.Err() once ctx is done.
+func (r *ImageRef) ExportJxlContext(ctx context.Context, params *JxlExportParams) ([]byte, *ImageMetadata, error) {
 	defer runtime.KeepAlive(r)
 	if params == nil {
 		params = NewJxlExportParams()
 	}
 
-	buf, err := vipsSaveJxlToBuffer(r.image, *params)
+	var buf []byte
+	err := vipsEvaluate(ctx, r.image, r.progress, func() (err error) {
+		buf, err = vipsSaveJxlToBuffer(r.image, *params)
+		return err
+	})
 	if err != nil {
 		return nil, nil, err
 	}
@@ -256,13 +344,22 @@ func (r *ImageRef) ExportJxl(params *JxlExportParams) ([]byte, *ImageMetadata, e
 
 // ExportMagick exports the image as Format set in param to a buffer.
 func (r *ImageRef) ExportMagick(params *MagickExportParams) ([]byte, *ImageMetadata, error) {
+	return r.ExportMagickContext(context.Background(), params)
+}
+
+// ExportMagickContext exports the image as Format set in param to a buffer, aborting the export with ctx.Err() once ctx is done.
+func (r *ImageRef) ExportMagickContext(ctx context.Context, params *MagickExportParams) ([]byte, *ImageMetadata, error) {
 	defer runtime.KeepAlive(r)
 	if params == nil {
 		params = NewMagickExportParams()
 		params.Format = "JPG"
 	}
 
-	buf, err := vipsSaveMagickToBuffer(r.image, *params)
+	var buf []byte
+	err := vipsEvaluate(ctx, r.image, r.progress, func() (err error) {
+		buf, err = vipsSaveMagickToBuffer(r.image, *params)
+		return err
+	})
 	if err != nil {
 		return nil, nil, err
 	}
@@ -270,13 +367,330 @@ func (r *ImageRef) ExportMagick(params *MagickExportParams) ([]byte, *ImageMetad
 	return buf, r.newMetadata(ImageTypeMagick), nil
 }
 
+// ExportJpegTo streams the image as JPEG to w without buffering the encoded bytes in memory.
+func (r *ImageRef) ExportJpegTo(w io.Writer, params *JpegExportParams) (*ImageMetadata, error) {
+	return r.ExportJpegToContext(context.Background(), w, params)
+}
+
+// ExportJpegToContext streams the image as JPEG to w like ExportJpegTo, aborting the export with ctx.Err() once ctx is done.
+func (r *ImageRef) ExportJpegToContext(ctx context.Context, w io.Writer, params *JpegExportParams) (*ImageMetadata, error) {
+	defer runtime.KeepAlive(r)
+	if params == nil {
+		params = NewJpegExportParams()
+	}
+
+	err := vipsEvaluate(ctx, r.image, r.progress, func() error {
+		return vipsSaveToWriter(createJpegSaveParams(r.image, *params), w)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.newMetadata(ImageTypeJPEG), nil
+}
+
+// ExportPngTo streams the image as PNG to w without buffering the encoded bytes in memory.
+func (r *ImageRef) ExportPngTo(w io.Writer, params *PngExportParams) (*ImageMetadata, error) {
+	return r.ExportPngToContext(context.Background(), w, params)
+}
+
+// ExportPngToContext streams the image as PNG to w like ExportPngTo, aborting the export with ctx.Err() once ctx is done.
+func (r *ImageRef) ExportPngToContext(ctx context.Context, w io.Writer, params *PngExportParams) (*ImageMetadata, error) {
+	defer runtime.KeepAlive(r)
+	if params == nil {
+		params = NewPngExportParams()
+	}
+
+	err := vipsEvaluate(ctx, r.image, r.progress, func() error {
+		return vipsSaveToWriter(createPngSaveParams(r.image, *params), w)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.newMetadata(ImageTypePNG), nil
+}
+
+// ExportWebpTo streams the image as WEBP to w without buffering the encoded bytes in memory.
+func (r *ImageRef) ExportWebpTo(w io.Writer, params *WebpExportParams) (*ImageMetadata, error) {
+	return r.ExportWebpToContext(context.Background(), w, params)
+}
+
+// ExportWebpToContext streams the image as WEBP to w like ExportWebpTo, aborting the export with ctx.Err() once ctx is done.
+func (r *ImageRef) ExportWebpToContext(ctx context.Context, w io.Writer, params *WebpExportParams) (*ImageMetadata, error) {
+	defer runtime.KeepAlive(r)
+	if params == nil {
+		params = NewWebpExportParams()
+	}
+
+	paramsWithIccProfile := *params
+	paramsWithIccProfile.IccProfile = r.optimizedIccProfile
+
+	err := vipsEvaluate(ctx, r.image, r.progress, func() error {
+		return vipsSaveToWriter(createWebpSaveParams(r.image, paramsWithIccProfile), w)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.newMetadata(ImageTypeWEBP), nil
+}
+
+// ExportHeifTo streams the image as HEIF to w without buffering the encoded bytes in memory.
+func (r *ImageRef) ExportHeifTo(w io.Writer, params *HeifExportParams) (*ImageMetadata, error) {
+	return r.ExportHeifToContext(context.Background(), w, params)
+}
+
+// ExportHeifToContext streams the image as HEIF to w like ExportHeifTo, aborting the export with ctx.Err() once ctx is done.
+func (r *ImageRef) ExportHeifToContext(ctx context.Context, w io.Writer, params *HeifExportParams) (*ImageMetadata, error) {
+	defer runtime.KeepAlive(r)
+	if params == nil {
+		params = NewHeifExportParams()
+	}
+
+	err := vipsEvaluate(ctx, r.image, r.progress, func() error {
+		return vipsSaveToWriter(createHeifSaveParams(r.image, *params), w)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.newMetadata(ImageTypeHEIF), nil
+}
+
+// ExportTiffTo streams the image as TIFF to w without buffering the encoded bytes in memory.
+func (r *ImageRef) ExportTiffTo(w io.Writer, params *TiffExportParams) (*ImageMetadata, error) {
+	return r.ExportTiffToContext(context.Background(), w, params)
+}
+
+// ExportTiffToContext streams the image as TIFF to w like ExportTiffTo, aborting the export with ctx.Err() once ctx is done.
+func (r *ImageRef) ExportTiffToContext(ctx context.Context, w io.Writer, params *TiffExportParams) (*ImageMetadata, error) {
+	defer runtime.KeepAlive(r)
+	if params == nil {
+		params = NewTiffExportParams()
+	}
+
+	err := vipsEvaluate(ctx, r.image, r.progress, func() error {
+		return vipsSaveToWriter(createTiffSaveParams(r.image, *params), w)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.newMetadata(ImageTypeTIFF), nil
+}
+
+// ExportGIFTo streams the image as GIF to w without buffering the encoded bytes in memory.
+func (r *ImageRef) ExportGIFTo(w io.Writer, params *GifExportParams) (*ImageMetadata, error) {
+	return r.ExportGIFToContext(context.Background(), w, params)
+}
+
+// ExportGIFToContext streams the image as GIF to w like ExportGIFTo, aborting the export with ctx.Err() once ctx is done.
+func (r *ImageRef) ExportGIFToContext(ctx context.Context, w io.Writer, params *GifExportParams) (*ImageMetadata, error) {
+	defer runtime.KeepAlive(r)
+	if params == nil {
+		params = NewGifExportParams()
+	}
+
+	err := vipsEvaluate(ctx, r.image, r.progress, func() error {
+		return vipsSaveToWriter(createGifSaveParams(r.image, *params), w)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.newMetadata(ImageTypeGIF), nil
+}
+
+// ExportAvifTo streams the image as AVIF to w without buffering the encoded bytes in memory.
+func (r *ImageRef) ExportAvifTo(w io.Writer, params *AvifExportParams) (*ImageMetadata, error) {
+	return r.ExportAvifToContext(context.Background(), w, params)
+}
+
+// ExportAvifToContext streams the image as AVIF to w like ExportAvifTo, aborting the export with ctx.Err() once ctx is done.
+func (r *ImageRef) ExportAvifToContext(ctx context.Context, w io.Writer, params *AvifExportParams) (*ImageMetadata, error) {
+	defer runtime.KeepAlive(r)
+	if params == nil {
+		params = NewAvifExportParams()
+	}
+
+	err := vipsEvaluate(ctx, r.image, r.progress, func() error {
+		return vipsSaveToWriter(createAvifSaveParams(r.image, *params), w)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.newMetadata(ImageTypeAVIF), nil
+}
+
+// ExportJp2kTo streams the image as JPEG2000 to w without buffering the encoded bytes in memory.
+func (r *ImageRef) ExportJp2kTo(w io.Writer, params *Jp2kExportParams) (*ImageMetadata, error) {
+	return r.ExportJp2kToContext(context.Background(), w, params)
+}
+
+// ExportJp2kToContext streams the image as JPEG2000 to w like ExportJp2kTo, aborting the export with ctx.Err() once ctx is done.
+func (r *ImageRef) ExportJp2kToContext(ctx context.Context, w io.Writer, params *Jp2kExportParams) (*ImageMetadata, error) {
+	defer runtime.KeepAlive(r)
+	if params == nil {
+		params = NewJp2kExportParams()
+	}
+
+	err := vipsEvaluate(ctx, r.image, r.progress, func() error {
+		return vipsSaveToWriter(createJp2kSaveParams(r.image, *params), w)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.newMetadata(ImageTypeJP2K), nil
+}
+
+// ExportJxlTo streams the image as JPEG XL to w without buffering the encoded bytes in memory.
+func (r *ImageRef) ExportJxlTo(w io.Writer, params *JxlExportParams) (*ImageMetadata, error) {
+	return r.ExportJxlToContext(context.Background(), w, params)
+}
+
+// ExportJxlToContext streams the image as JPEG XL to w like ExportJxlTo, aborting the export with ctx.Err() once ctx is done.
+func (r *ImageRef) ExportJxlToContext(ctx context.Context, w io.Writer, params *JxlExportParams) (*ImageMetadata, error) {
+	defer runtime.KeepAlive(r)
+	if params == nil {
+		params = NewJxlExportParams()
+	}
+
+	err := vipsEvaluate(ctx, r.image, r.progress, func() error {
+		return vipsSaveToWriter(createJxlSaveParams(r.image, *params), w)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.newMetadata(ImageTypeJXL), nil
+}
+
+// SaveToFile encodes the image straight to the file at path. The saver is picked from
+// params, which must be one of the format-specific export params such as *JpegExportParams,
+// or from the extension of path when params is nil, in which case format defaults are used.
+func (r *ImageRef) SaveToFile(path string, params interface{}) (*ImageMetadata, error) {
+	return r.SaveToFileContext(context.Background(), path, params)
+}
+
+// SaveToFileContext encodes the image to the file at path like SaveToFile, aborting the export with ctx.Err()
+// once ctx is done. The file may be left partially written when the export is aborted.
+func (r *ImageRef) SaveToFileContext(ctx context.Context, path string, params interface{}) (*ImageMetadata, error) {
+	defer runtime.KeepAlive(r)
+	if params == nil {
+		var err error
+		if params, err = newExportParamsForFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	// The C save params are only created once the save runs, as only the save frees them
+	var create func() C.struct_SaveParams
+	var format ImageType
+
+	switch v := params.(type) {
+	case *JpegExportParams:
+		if v == nil {
+			v = NewJpegExportParams()
+		}
+		create, format = func() C.struct_SaveParams { return createJpegSaveParams(r.image, *v) }, ImageTypeJPEG
+	case *PngExportParams:
+		if v == nil {
+			v = NewPngExportParams()
+		}
+		create, format = func() C.struct_SaveParams { return createPngSaveParams(r.image, *v) }, ImageTypePNG
+	case *WebpExportParams:
+		if v == nil {
+			v = NewWebpExportParams()
+		}
+		paramsWithIccProfile := *v
+		paramsWithIccProfile.IccProfile = r.optimizedIccProfile
+		create, format = func() C.struct_SaveParams { return createWebpSaveParams(r.image, paramsWithIccProfile) }, ImageTypeWEBP
+	case *HeifExportParams:
+		if v == nil {
+			v = NewHeifExportParams()
+		}
+		create, format = func() C.struct_SaveParams { return createHeifSaveParams(r.image, *v) }, ImageTypeHEIF
+	case *TiffExportParams:
+		if v == nil {
+			v = NewTiffExportParams()
+		}
+		create, format = func() C.struct_SaveParams { return createTiffSaveParams(r.image, *v) }, ImageTypeTIFF
+	case *GifExportParams:
+		if v == nil {
+			v = NewGifExportParams()
+		}
+		create, format = func() C.struct_SaveParams { return createGifSaveParams(r.image, *v) }, ImageTypeGIF
+	case *AvifExportParams:
+		if v == nil {
+			v = NewAvifExportParams()
+		}
+		create, format = func() C.struct_SaveParams { return createAvifSaveParams(r.image, *v) }, ImageTypeAVIF
+	case *Jp2kExportParams:
+		if v == nil {
+			v = NewJp2kExportParams()
+		}
+		create, format = func() C.struct_SaveParams { return createJp2kSaveParams(r.image, *v) }, ImageTypeJP2K
+	case *JxlExportParams:
+		if v == nil {
+			v = NewJxlExportParams()
+		}
+		create, format = func() C.struct_SaveParams { return createJxlSaveParams(r.image, *v) }, ImageTypeJXL
+	default:
+		return nil, fmt.Errorf("cannot save to file with params of type %T: %w", params, ErrUnsupportedSaveFormat)
+	}
+
+	err := vipsEvaluate(ctx, r.image, r.progress, func() error {
+		return vipsSaveToFile(create(), path)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.newMetadata(format), nil
+}
+
+// newExportParamsForFile returns the default export params for the extension of path
+func newExportParamsForFile(path string) (interface{}, error) {
+	switch saveTypeFromFileExt(path) {
+	case ImageTypeJPEG:
+		return NewJpegExportParams(), nil
+	case ImageTypePNG:
+		return NewPngExportParams(), nil
+	case ImageTypeWEBP:
+		return NewWebpExportParams(), nil
+	case ImageTypeHEIF:
+		return NewHeifExportParams(), nil
+	case ImageTypeTIFF:
+		return NewTiffExportParams(), nil
+	case ImageTypeGIF:
+		return NewGifExportParams(), nil
+	case ImageTypeAVIF:
+		return NewAvifExportParams(), nil
+	case ImageTypeJP2K:
+		return NewJp2kExportParams(), nil
+	case ImageTypeJXL:
+		return NewJxlExportParams(), nil
+	default:
+		return nil, fmt.Errorf("cannot determine output format from file extension of %q: %w", path, ErrUnsupportedSaveFormat)
+	}
+}
+
 // ToBytes writes the image to memory in VIPs format and returns the raw bytes, useful for storage.
 func (r *ImageRef) ToBytes() ([]byte, error) {
 	defer runtime.KeepAlive(r)
 	var cSize C.size_t
-	cData := C.vips_image_write_to_memory(r.image, &cSize)
-	if cData == nil {
-		return nil, errors.New("failed to write image to memory")
+	var cData unsafe.Pointer
+	err := r.evaluate(func() error {
+		if cData = C.vips_image_write_to_memory(r.image, &cSize); cData == nil {
+			return errors.New("failed to write image to memory")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	defer C.free(cData)
 
@@ -337,9 +751,15 @@ func (r *ImageRef) ToGoImage() (image.Image, error) {
 
 	// Extract raw pixel data
 	var cSize C.size_t
-	cData := C.vips_image_write_to_memory(tmp, &cSize)
-	if cData == nil {
-		return nil, errors.New("failed to write image to memory")
+	var cData unsafe.Pointer
+	err = vipsEvaluate(context.Background(), tmp, r.progress, func() error {
+		if cData = C.vips_image_write_to_memory(tmp, &cSize); cData == nil {
+			return errors.New("failed to write image to memory")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	defer C.free(cData)
 
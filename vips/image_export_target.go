@@ -0,0 +1,227 @@
+package vips
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+)
+
+// Default quality range searched by ExportWithTarget
+const (
+	DefaultTargetMinQuality = 10
+	DefaultTargetMaxQuality = 95
+)
+
+// Target describes the encoding ExportWithTarget searches for. At least one of MaxBytes
+// and MinSSIM must be set.
+type Target struct {
+	// MaxBytes is the largest acceptable encoded size, or 0 for no limit
+	MaxBytes int
+	// MinSSIM is the smallest acceptable structural similarity to the original image,
+	// between 0 and 1, or 0 for no limit
+	MinSSIM float64
+	// MinQuality and MaxQuality bound the qualities tried. Zero values use
+	// DefaultTargetMinQuality and DefaultTargetMaxQuality.
+	MinQuality int
+	MaxQuality int
+}
+
+// targetEncoding is one encoding tried by ExportWithTarget
+type targetEncoding struct {
+	quality  int
+	buf      []byte
+	metadata *ImageMetadata
+}
+
+// ExportWithTarget encodes the image with the lowest quality setting whose decoded result is
+// at least target.MinSSIM similar to the image, or, when only target.MaxBytes is set, with the
+// highest quality that fits in target.MaxBytes. The quality is found by binary search,
+// encoding and decoding in memory. params is one of *JpegExportParams, *WebpExportParams,
+// *AvifExportParams, *HeifExportParams, *JxlExportParams or *Jp2kExportParams; its Quality
+// is ignored and the other settings are used as given. It returns the encoded bytes, their
+// metadata and the quality chosen. If no quality in range meets the target, the error wraps
+// ErrTargetUnreachable.
+func (r *ImageRef) ExportWithTarget(params interface{}, target Target) ([]byte, *ImageMetadata, int, error) {
+	defer runtime.KeepAlive(r)
+	if target.MaxBytes <= 0 && target.MinSSIM <= 0 {
+		return nil, nil, 0, errors.New("target must set MaxBytes or MinSSIM")
+	}
+
+	minQuality, maxQuality := target.MinQuality, target.MaxQuality
+	if minQuality <= 0 {
+		minQuality = DefaultTargetMinQuality
+	}
+	if maxQuality <= 0 {
+		maxQuality = DefaultTargetMaxQuality
+	}
+	if minQuality > maxQuality {
+		return nil, nil, 0, errors.New("target MinQuality must not exceed MaxQuality")
+	}
+
+	encode, err := r.qualityEncoder(params)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	var reference *ImageRef
+	if target.MinSSIM > 0 {
+		if reference, err = ssimReference(r); err != nil {
+			return nil, nil, 0, err
+		}
+		defer reference.Close()
+	}
+
+	accept := func(e *targetEncoding) (bool, error) {
+		if target.MinSSIM <= 0 {
+			return len(e.buf) <= target.MaxBytes, nil
+		}
+		ssim, err := encodedSSIM(reference, e.buf)
+		if err != nil {
+			return false, err
+		}
+		return ssim >= target.MinSSIM, nil
+	}
+
+	// Size grows with quality, so a byte budget is met by every quality up to some limit and
+	// a similarity target by every quality from some threshold. Search for that boundary.
+	var best *targetEncoding
+	low, high := minQuality, maxQuality
+	for low <= high {
+		quality := low + (high-low)/2
+		buf, metadata, err := encode(quality)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		e := &targetEncoding{quality: quality, buf: buf, metadata: metadata}
+
+		ok, err := accept(e)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+
+		switch {
+		case ok && target.MinSSIM > 0:
+			best, high = e, quality-1
+		case ok:
+			best, low = e, quality+1
+		case target.MinSSIM > 0:
+			low = quality + 1
+		default:
+			high = quality - 1
+		}
+	}
+
+	if best == nil {
+		return nil, nil, 0, fmt.Errorf("no quality between %d and %d meets the target: %w", minQuality, maxQuality, ErrTargetUnreachable)
+	}
+	if target.MaxBytes > 0 && len(best.buf) > target.MaxBytes {
+		return nil, nil, 0, fmt.Errorf("quality %d meets the similarity target but needs %d bytes: %w", best.quality, len(best.buf), ErrTargetUnreachable)
+	}
+	return best.buf, best.metadata, best.quality, nil
+}
+
+// qualityEncoder returns a function which encodes the image with params at a given quality
+func (r *ImageRef) qualityEncoder(params interface{}) (func(quality int) ([]byte, *ImageMetadata, error), error) {
+	switch v := params.(type) {
+	case *JpegExportParams:
+		p := NewJpegExportParams()
+		if v != nil {
+			p = v
+		}
+		return func(quality int) ([]byte, *ImageMetadata, error) {
+			q := *p
+			q.Quality = quality
+			return r.ExportJpeg(&q)
+		}, nil
+	case *WebpExportParams:
+		p := NewWebpExportParams()
+		if v != nil {
+			p = v
+		}
+		return func(quality int) ([]byte, *ImageMetadata, error) {
+			q := *p
+			q.Quality = quality
+			return r.ExportWebp(&q)
+		}, nil
+	case *AvifExportParams:
+		p := NewAvifExportParams()
+		if v != nil {
+			p = v
+		}
+		return func(quality int) ([]byte, *ImageMetadata, error) {
+			q := *p
+			q.Quality = quality
+			return r.ExportAvif(&q)
+		}, nil
+	case *HeifExportParams:
+		p := NewHeifExportParams()
+		if v != nil {
+			p = v
+		}
+		return func(quality int) ([]byte, *ImageMetadata, error) {
+			q := *p
+			q.Quality = quality
+			return r.ExportHeif(&q)
+		}, nil
+	case *JxlExportParams:
+		p := NewJxlExportParams()
+		if v != nil {
+			p = v
+		}
+		return func(quality int) ([]byte, *ImageMetadata, error) {
+			q := *p
+			q.Quality = quality
+			return r.ExportJxl(&q)
+		}, nil
+	case *Jp2kExportParams:
+		p := NewJp2kExportParams()
+		if v != nil {
+			p = v
+		}
+		return func(quality int) ([]byte, *ImageMetadata, error) {
+			q := *p
+			q.Quality = quality
+			return r.ExportJp2k(&q)
+		}, nil
+	default:
+		return nil, fmt.Errorf("cannot search quality for params of type %T: %w", params, ErrUnsupportedSaveFormat)
+	}
+}
+
+// ssimReference prepares a copy of img for comparison with decoded encodings of it:
+// sRGB without alpha, as most lossy formats are compared visually.
+func ssimReference(img *ImageRef) (*ImageRef, error) {
+	ref, err := img.Copy()
+	if err != nil {
+		return nil, err
+	}
+	if err := normalizeForSSIM(ref); err != nil {
+		ref.Close()
+		return nil, err
+	}
+	return ref, nil
+}
+
+func normalizeForSSIM(img *ImageRef) error {
+	if err := img.ToColorSpace(InterpretationSRGB); err != nil {
+		return err
+	}
+	if img.Bands() > 3 {
+		return img.ExtractBand(0, 3)
+	}
+	return nil
+}
+
+// encodedSSIM decodes buf and returns its similarity to reference
+func encodedSSIM(reference *ImageRef, buf []byte) (float64, error) {
+	decoded, err := NewImageFromBuffer(buf)
+	if err != nil {
+		return 0, err
+	}
+	defer decoded.Close()
+
+	if err := normalizeForSSIM(decoded); err != nil {
+		return 0, err
+	}
+	return Compare(reference, decoded, MetricSSIM)
+}
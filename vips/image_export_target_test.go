@@ -0,0 +1,83 @@
+package vips
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageRef_ExportWithTarget_MaxBytes(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	img, err := NewImageFromFile(resources + "jpg-24bit.jpg")
+	require.NoError(t, err)
+	defer img.Close()
+
+	full, _, err := img.ExportJpeg(&JpegExportParams{Quality: DefaultTargetMaxQuality})
+	require.NoError(t, err)
+
+	budget := len(full) / 2
+	buf, metadata, quality, err := img.ExportWithTarget(NewJpegExportParams(), Target{MaxBytes: budget})
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(buf), budget)
+	assert.Less(t, quality, DefaultTargetMaxQuality)
+	assert.Equal(t, ImageTypeJPEG, metadata.Format)
+
+	// The next quality up must not fit, otherwise the search stopped too early
+	larger, _, err := img.ExportJpeg(&JpegExportParams{Quality: quality + 1})
+	require.NoError(t, err)
+	assert.Greater(t, len(larger), budget)
+}
+
+func TestImageRef_ExportWithTarget_MinSSIM(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	img, err := NewImageFromFile(resources + "png-24bit.png")
+	require.NoError(t, err)
+	defer img.Close()
+
+	buf, _, quality, err := img.ExportWithTarget(&WebpExportParams{StripMetadata: true}, Target{MinSSIM: 0.95})
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, quality, DefaultTargetMinQuality)
+
+	reference, err := ssimReference(img)
+	require.NoError(t, err)
+	defer reference.Close()
+
+	ssim, err := encodedSSIM(reference, buf)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, ssim, 0.95)
+}
+
+func TestImageRef_ExportWithTarget_Unreachable(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	img, err := NewImageFromFile(resources + "jpg-24bit.jpg")
+	require.NoError(t, err)
+	defer img.Close()
+
+	_, _, _, err = img.ExportWithTarget(NewJpegExportParams(), Target{MaxBytes: 10})
+	assert.True(t, errors.Is(err, ErrTargetUnreachable))
+
+	_, _, _, err = img.ExportWithTarget(NewJpegExportParams(), Target{MinSSIM: 0.5, MaxBytes: 10})
+	assert.True(t, errors.Is(err, ErrTargetUnreachable))
+}
+
+func TestImageRef_ExportWithTarget_InvalidParams(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	img, err := NewImageFromFile(resources + "jpg-24bit.jpg")
+	require.NoError(t, err)
+	defer img.Close()
+
+	_, _, _, err = img.ExportWithTarget(NewJpegExportParams(), Target{})
+	assert.Error(t, err)
+
+	_, _, _, err = img.ExportWithTarget(NewPngExportParams(), Target{MaxBytes: 1000})
+	assert.True(t, errors.Is(err, ErrUnsupportedSaveFormat))
+
+	_, _, _, err = img.ExportWithTarget(NewJpegExportParams(), Target{MaxBytes: 1000, MinQuality: 80, MaxQuality: 20})
+	assert.Error(t, err)
+}
@@ -0,0 +1,163 @@
+package vips
+
+// #include "image.h"
+import "C"
+
+import (
+	"runtime"
+)
+
+// FrequencyMaskOptions are the optional parameters of the frequency mask constructors
+type FrequencyMaskOptions struct {
+	// Uchar outputs a uchar image scaled to 0-255 instead of float
+	Uchar bool
+	// NoDC leaves the DC (zero frequency) pixel unset
+	NoDC bool
+	// Reject inverts the sense of the filter, e.g. turning a low pass into a high pass
+	Reject bool
+	// Optical rotates quadrants so the DC pixel is in the centre, as when viewing a spectrum
+	Optical bool
+}
+
+func (o *FrequencyMaskOptions) values() (uchar, nodc, reject, optical *bool) {
+	if o == nil {
+		return nil, nil, nil, nil
+	}
+	return &o.Uchar, &o.NoDC, &o.Reject, &o.Optical
+}
+
+func newFrequencyMask(out *C.VipsImage, err error) (*ImageRef, error) {
+	if err != nil {
+		return nil, err
+	}
+	return newImageRef(out, ImageTypeUnknown, ImageTypeUnknown, nil), nil
+}
+
+// NewIdealMask creates an ideal low pass filter passing frequencies below cutoff, given as a fraction of the maximum frequency
+func NewIdealMask(width, height int, cutoff float64, opts *FrequencyMaskOptions) (*ImageRef, error) {
+	uchar, nodc, reject, optical := opts.values()
+	return newFrequencyMask(vipsGenMaskIdeal(width, height, cutoff,
+		&MaskIdealOptions{Uchar: uchar, Nodc: nodc, Reject: reject, Optical: optical}))
+}
+
+// NewIdealRingMask creates an ideal ring filter passing frequencies within ringWidth of cutoff
+func NewIdealRingMask(width, height int, cutoff, ringWidth float64, opts *FrequencyMaskOptions) (*ImageRef, error) {
+	uchar, nodc, reject, optical := opts.values()
+	return newFrequencyMask(vipsGenMaskIdealRing(width, height, cutoff, ringWidth,
+		&MaskIdealRingOptions{Uchar: uchar, Nodc: nodc, Reject: reject, Optical: optical}))
+}
+
+// NewIdealBandMask creates an ideal band filter passing a circle of radius around the frequency (cutoffX, cutoffY)
+func NewIdealBandMask(width, height int, cutoffX, cutoffY, radius float64, opts *FrequencyMaskOptions) (*ImageRef, error) {
+	uchar, nodc, reject, optical := opts.values()
+	return newFrequencyMask(vipsGenMaskIdealBand(width, height, cutoffX, cutoffY, radius,
+		&MaskIdealBandOptions{Uchar: uchar, Nodc: nodc, Reject: reject, Optical: optical}))
+}
+
+// NewGaussianMask creates a Gaussian low pass filter which has amplitudeCutoff at frequency cutoff
+func NewGaussianMask(width, height int, cutoff, amplitudeCutoff float64, opts *FrequencyMaskOptions) (*ImageRef, error) {
+	uchar, nodc, reject, optical := opts.values()
+	return newFrequencyMask(vipsGenMaskGaussian(width, height, cutoff, amplitudeCutoff,
+		&MaskGaussianOptions{Uchar: uchar, Nodc: nodc, Reject: reject, Optical: optical}))
+}
+
+// NewGaussianRingMask creates a Gaussian ring filter of ringWidth around cutoff
+func NewGaussianRingMask(width, height int, cutoff, amplitudeCutoff, ringWidth float64, opts *FrequencyMaskOptions) (*ImageRef, error) {
+	uchar, nodc, reject, optical := opts.values()
+	return newFrequencyMask(vipsGenMaskGaussianRing(width, height, cutoff, amplitudeCutoff, ringWidth,
+		&MaskGaussianRingOptions{Uchar: uchar, Nodc: nodc, Reject: reject, Optical: optical}))
+}
+
+// NewGaussianBandMask creates a Gaussian band filter of radius around the frequency (cutoffX, cutoffY)
+func NewGaussianBandMask(width, height int, cutoffX, cutoffY, radius, amplitudeCutoff float64, opts *FrequencyMaskOptions) (*ImageRef, error) {
+	uchar, nodc, reject, optical := opts.values()
+	return newFrequencyMask(vipsGenMaskGaussianBand(width, height, cutoffX, cutoffY, radius, amplitudeCutoff,
+		&MaskGaussianBandOptions{Uchar: uchar, Nodc: nodc, Reject: reject, Optical: optical}))
+}
+
+// NewButterworthMask creates a Butterworth low pass filter of the given order which has amplitudeCutoff at frequency cutoff
+func NewButterworthMask(width, height int, order, cutoff, amplitudeCutoff float64, opts *FrequencyMaskOptions) (*ImageRef, error) {
+	uchar, nodc, reject, optical := opts.values()
+	return newFrequencyMask(vipsGenMaskButterworth(width, height, order, cutoff, amplitudeCutoff,
+		&MaskButterworthOptions{Uchar: uchar, Nodc: nodc, Reject: reject, Optical: optical}))
+}
+
+// NewButterworthRingMask creates a Butterworth ring filter of ringWidth around cutoff
+func NewButterworthRingMask(width, height int, order, cutoff, amplitudeCutoff, ringWidth float64, opts *FrequencyMaskOptions) (*ImageRef, error) {
+	uchar, nodc, reject, optical := opts.values()
+	return newFrequencyMask(vipsGenMaskButterworthRing(width, height, order, cutoff, amplitudeCutoff, ringWidth,
+		&MaskButterworthRingOptions{Uchar: uchar, Nodc: nodc, Reject: reject, Optical: optical}))
+}
+
+// NewButterworthBandMask creates a Butterworth band filter of radius around the frequency (cutoffX, cutoffY),
+// useful to reject the peaks of periodic noise such as halftone screens.
+func NewButterworthBandMask(width, height int, order, cutoffX, cutoffY, radius, amplitudeCutoff float64, opts *FrequencyMaskOptions) (*ImageRef, error) {
+	uchar, nodc, reject, optical := opts.values()
+	return newFrequencyMask(vipsGenMaskButterworthBand(width, height, order, cutoffX, cutoffY, radius, amplitudeCutoff,
+		&MaskButterworthBandOptions{Uchar: uchar, Nodc: nodc, Reject: reject, Optical: optical}))
+}
+
+// NewFractalMask creates a filter which gives fractal noise of the given dimension, between 2 and 3, when applied to Gaussian noise
+func NewFractalMask(width, height int, fractalDimension float64, opts *FrequencyMaskOptions) (*ImageRef, error) {
+	uchar, nodc, reject, optical := opts.values()
+	return newFrequencyMask(vipsGenMaskFractal(width, height, fractalDimension,
+		&MaskFractalOptions{Uchar: uchar, Nodc: nodc, Reject: reject, Optical: optical}))
+}
+
+// Sines creates a float image of a 2D sine wave with hfreq horizontal and vfreq vertical periods across the image
+func Sines(width, height int, hfreq, vfreq float64) (*ImageRef, error) {
+	img, err := vipsGenSines(width, height, &SinesOptions{Hfreq: &hfreq, Vfreq: &vfreq})
+	if err != nil {
+		return nil, err
+	}
+	return newImageRef(img, ImageTypeUnknown, ImageTypeUnknown, nil), nil
+}
+
+// FFT transforms the image to the frequency domain as a complex image
+func (r *ImageRef) FFT() error {
+	defer runtime.KeepAlive(r)
+	out, err := vipsGenFwfft(r.image)
+	if err != nil {
+		return err
+	}
+	r.setImage(out)
+	return nil
+}
+
+// InverseFFT transforms a complex frequency domain image back to the spatial domain.
+// When real is true only the real part is kept, giving a float image.
+func (r *ImageRef) InverseFFT(real bool) error {
+	defer runtime.KeepAlive(r)
+	out, err := vipsGenInvfft(r.image, &InvfftOptions{Real: &real})
+	if err != nil {
+		return err
+	}
+	r.setImage(out)
+	return nil
+}
+
+// FreqMult filters the image by multiplying its Fourier transform with mask, a frequency
+// mask such as one made by NewButterworthMask. The image is transformed to and from the
+// frequency domain as needed.
+func (r *ImageRef) FreqMult(mask *ImageRef) error {
+	defer runtime.KeepAlive(r)
+	defer runtime.KeepAlive(mask)
+	out, err := vipsGenFreqmult(r.image, mask.image)
+	if err != nil {
+		return err
+	}
+	r.setImage(out)
+	return nil
+}
+
+// PowerSpectrum replaces the image with its power spectrum, a one band uchar image with
+// the DC component in the centre, for inspecting periodic noise.
+func (r *ImageRef) PowerSpectrum() error {
+	defer runtime.KeepAlive(r)
+	out, err := vipsGenSpectrum(r.image)
+	if err != nil {
+		return err
+	}
+	r.setImage(out)
+	return nil
+}
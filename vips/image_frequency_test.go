@@ -0,0 +1,118 @@
+package vips
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// maxAbsDifference returns the largest absolute pixel difference between a and b
+func maxAbsDifference(t *testing.T, a, b *ImageRef) float64 {
+	diff, err := a.Copy()
+	require.NoError(t, err)
+	defer diff.Close()
+
+	require.NoError(t, diff.Subtract(b))
+	require.NoError(t, diff.Abs())
+	require.NoError(t, diff.Linear([]float64{-1}, []float64{0}))
+
+	min, _, _, err := diff.Min()
+	require.NoError(t, err)
+	return -min
+}
+
+func TestImageRef_FFT_RoundTrip(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	original, err := Sines(128, 128, 4, 2)
+	require.NoError(t, err)
+	defer original.Close()
+
+	img, err := original.Copy()
+	require.NoError(t, err)
+	defer img.Close()
+
+	require.NoError(t, img.FFT())
+	assert.Equal(t, BandFormatDpComplex, img.BandFormat())
+
+	require.NoError(t, img.InverseFFT(true))
+	assert.Equal(t, original.Width(), img.Width())
+	assert.Equal(t, original.Height(), img.Height())
+	assert.InDelta(t, 0, maxAbsDifference(t, original, img), 1e-3)
+}
+
+func TestImageRef_FreqMult(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	// A low frequency wave with high frequency noise on top
+	low, err := Sines(128, 128, 2, 0)
+	require.NoError(t, err)
+	defer low.Close()
+
+	noise, err := Sines(128, 128, 40, 40)
+	require.NoError(t, err)
+	defer noise.Close()
+
+	img, err := low.Copy()
+	require.NoError(t, err)
+	defer img.Close()
+	require.NoError(t, img.Add(noise))
+
+	mask, err := NewButterworthMask(128, 128, 6, 0.2, 0.5, nil)
+	require.NoError(t, err)
+	defer mask.Close()
+
+	require.NoError(t, img.FreqMult(mask))
+	assert.Equal(t, 1, img.Bands())
+
+	// FreqMult output is scaled, compare against the filtered wave alone
+	expected, err := low.Copy()
+	require.NoError(t, err)
+	defer expected.Close()
+	require.NoError(t, expected.FreqMult(mask))
+
+	assert.Less(t, maxAbsDifference(t, expected, img), maxAbsDifference(t, low, noise))
+}
+
+func TestImageRef_PowerSpectrum(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	img, err := Sines(64, 64, 8, 0)
+	require.NoError(t, err)
+	defer img.Close()
+
+	require.NoError(t, img.PowerSpectrum())
+	assert.Equal(t, 64, img.Width())
+	assert.Equal(t, BandFormatUchar, img.BandFormat())
+}
+
+func TestFrequencyMasks(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	constructors := map[string]func() (*ImageRef, error){
+		"ideal":            func() (*ImageRef, error) { return NewIdealMask(64, 64, 0.5, nil) },
+		"ideal ring":       func() (*ImageRef, error) { return NewIdealRingMask(64, 64, 0.5, 0.1, nil) },
+		"ideal band":       func() (*ImageRef, error) { return NewIdealBandMask(64, 64, 0.3, 0.3, 0.1, nil) },
+		"gaussian":         func() (*ImageRef, error) { return NewGaussianMask(64, 64, 0.5, 0.5, nil) },
+		"gaussian ring":    func() (*ImageRef, error) { return NewGaussianRingMask(64, 64, 0.5, 0.5, 0.1, nil) },
+		"gaussian band":    func() (*ImageRef, error) { return NewGaussianBandMask(64, 64, 0.3, 0.3, 0.1, 0.5, nil) },
+		"butterworth":      func() (*ImageRef, error) { return NewButterworthMask(64, 64, 2, 0.5, 0.5, nil) },
+		"butterworth ring": func() (*ImageRef, error) { return NewButterworthRingMask(64, 64, 2, 0.5, 0.5, 0.1, nil) },
+		"butterworth band": func() (*ImageRef, error) { return NewButterworthBandMask(64, 64, 2, 0.3, 0.3, 0.1, 0.5, nil) },
+		"fractal":          func() (*ImageRef, error) { return NewFractalMask(64, 64, 2.5, nil) },
+		"uchar reject": func() (*ImageRef, error) {
+			return NewIdealMask(64, 64, 0.5, &FrequencyMaskOptions{Uchar: true, Reject: true, Optical: true})
+		},
+	}
+
+	for name, constructor := range constructors {
+		t.Run(name, func(t *testing.T) {
+			mask, err := constructor()
+			require.NoError(t, err)
+			defer mask.Close()
+			assert.Equal(t, 64, mask.Width())
+			assert.Equal(t, 64, mask.Height())
+		})
+	}
+}
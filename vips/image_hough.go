@@ -0,0 +1,269 @@
+package vips
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"runtime"
+	"sort"
+)
+
+// Default sizes of the Hough transform parameter spaces, matching libvips
+const (
+	DefaultHoughLineWidth       = 256
+	DefaultHoughLineHeight      = 256
+	DefaultHoughCircleScale     = 3
+	DefaultHoughCircleMinRadius = 10
+	DefaultHoughCircleMaxRadius = 20
+)
+
+// Line is a straight line found by a Hough transform. It is the set of pixels where
+// x*cos(Theta) + y*sin(Theta) = Rho, with Theta in degrees in [0, 180) and Rho the distance
+// in pixels from the top left corner of the image. For images which are not square Theta
+// differs from the angle of the accumulator column, see HoughLineAccumulator.
+type Line struct {
+	Rho   float64
+	Theta float64
+	Votes int
+}
+
+// Circle is a circle found by a Hough transform, with its centre and radius in pixels
+type Circle struct {
+	X     int
+	Y     int
+	R     int
+	Votes int
+}
+
+// HoughLineParams are the parameters of HoughLine. Zero values use the defaults.
+type HoughLineParams struct {
+	// Width is the number of angle steps in the accumulator
+	Width int
+	// Height is the number of distance steps in the accumulator
+	Height int
+}
+
+// HoughCircleParams are the parameters of HoughCircle. Zero values use the defaults.
+type HoughCircleParams struct {
+	// Scale divides the accumulator size and radius steps, trading accuracy for speed
+	Scale int
+	// MinRadius is the smallest radius searched for
+	MinRadius int
+	// MaxRadius is the largest radius searched for
+	MaxRadius int
+}
+
+// HoughLineAccumulator is the parameter space image produced by HoughLine. libvips votes
+// with coordinates normalised to the image size, so that x/W*cos(t) + y/H*sin(t) = r for
+// an image of W by H pixels. Column i is the angle t = 180*i/Width degrees and row j the
+// distance r from j/Height to (j+1)/Height. Peaks converts them back to pixels.
+type HoughLineAccumulator struct {
+	*ImageRef
+	imageWidth  float64
+	imageHeight float64
+}
+
+// HoughCircleAccumulator is the parameter space image produced by HoughCircle. Each band
+// holds the votes for one radius and pixels map to circle centres.
+type HoughCircleAccumulator struct {
+	*ImageRef
+	scale     int
+	minRadius int
+}
+
+// HoughLine finds straight lines in a one band image, usually the output of an edge detector
+// such as Canny. It returns the accumulator; use its Peaks method to extract the lines.
+func (r *ImageRef) HoughLine(params *HoughLineParams) (*HoughLineAccumulator, error) {
+	defer runtime.KeepAlive(r)
+	width, height := DefaultHoughLineWidth, DefaultHoughLineHeight
+	if params != nil {
+		if params.Width > 0 {
+			width = params.Width
+		}
+		if params.Height > 0 {
+			height = params.Height
+		}
+	}
+
+	out, err := vipsGenHoughLine(r.image, &HoughLineOptions{Width: &width, Height: &height})
+	if err != nil {
+		return nil, err
+	}
+
+	return &HoughLineAccumulator{
+		ImageRef:    newImageRef(out, r.format, r.originalFormat, nil),
+		imageWidth:  float64(r.Width()),
+		imageHeight: float64(r.Height()),
+	}, nil
+}
+
+// HoughCircle finds circles in a one band image, usually the output of an edge detector
+// such as Canny. It returns the accumulator; use its Peaks method to extract the circles.
+func (r *ImageRef) HoughCircle(params *HoughCircleParams) (*HoughCircleAccumulator, error) {
+	defer runtime.KeepAlive(r)
+	scale := DefaultHoughCircleScale
+	minRadius := DefaultHoughCircleMinRadius
+	maxRadius := DefaultHoughCircleMaxRadius
+	if params != nil {
+		if params.Scale > 0 {
+			scale = params.Scale
+		}
+		if params.MinRadius > 0 {
+			minRadius = params.MinRadius
+		}
+		if params.MaxRadius > 0 {
+			maxRadius = params.MaxRadius
+		}
+	}
+	if maxRadius < minRadius {
+		return nil, errors.New("hough circle max radius must not be less than min radius")
+	}
+
+	out, err := vipsGenHoughCircle(r.image, &HoughCircleOptions{Scale: &scale, MinRadius: &minRadius, MaxRadius: &maxRadius})
+	if err != nil {
+		return nil, err
+	}
+
+	return &HoughCircleAccumulator{
+		ImageRef:  newImageRef(out, r.format, r.originalFormat, nil),
+		scale:     scale,
+		minRadius: minRadius,
+	}, nil
+}
+
+// Peaks returns up to n lines with the most votes, strongest first. Only local maxima of
+// the accumulator are considered so that near duplicates of the same line are skipped.
+func (a *HoughLineAccumulator) Peaks(n int) ([]Line, error) {
+	acc, err := readHoughAccumulator(a.ImageRef)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []Line
+	for _, p := range acc.peaks(n) {
+		line := houghLine(p.x, p.y, acc.width, acc.height, a.imageWidth, a.imageHeight)
+		line.Votes = p.votes
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// houghLine converts the accumulator cell at column x and row y, of an accumulator of
+// accWidth by accHeight, to a line in the pixel coordinates of a width by height image
+func houghLine(x, y, accWidth, accHeight int, width, height float64) Line {
+	t := float64(x) * math.Pi / float64(accWidth)
+	r := (float64(y) + 0.5) / float64(accHeight)
+
+	// x/W*cos(t) + y/H*sin(t) = r is the line x*a + y*b = r, whose normal has the
+	// direction (a, b) and lies in [0, 180) degrees as b is never negative
+	a, b := math.Cos(t)/width, math.Sin(t)/height
+	norm := math.Hypot(a, b)
+	return Line{
+		Rho:   r / norm,
+		Theta: math.Atan2(b, a) * 180 / math.Pi,
+	}
+}
+
+// Peaks returns up to n circles with the most votes, strongest first. Only local maxima of
+// the accumulator are considered so that near duplicates of the same circle are skipped.
+func (a *HoughCircleAccumulator) Peaks(n int) ([]Circle, error) {
+	acc, err := readHoughAccumulator(a.ImageRef)
+	if err != nil {
+		return nil, err
+	}
+
+	var circles []Circle
+	for _, p := range acc.peaks(n) {
+		circles = append(circles, Circle{
+			X:     p.x*a.scale + a.scale/2,
+			Y:     p.y*a.scale + a.scale/2,
+			R:     a.minRadius + p.band*a.scale,
+			Votes: p.votes,
+		})
+	}
+	return circles, nil
+}
+
+type houghAccumulator struct {
+	width, height, bands int
+	votes                []uint32
+}
+
+type houghPeak struct {
+	x, y, band int
+	votes      int
+}
+
+// readHoughAccumulator copies the votes of an accumulator image into Go memory
+func readHoughAccumulator(img *ImageRef) (*houghAccumulator, error) {
+	acc, err := img.Copy()
+	if err != nil {
+		return nil, err
+	}
+	defer acc.Close()
+
+	if acc.BandFormat() != BandFormatUint {
+		if err := acc.Cast(BandFormatUint); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := acc.ToBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	h := &houghAccumulator{width: acc.Width(), height: acc.Height(), bands: acc.Bands()}
+	if len(data) != h.width*h.height*h.bands*4 {
+		return nil, errors.New("unexpected hough accumulator size")
+	}
+	h.votes = make([]uint32, len(data)/4)
+	for i := range h.votes {
+		h.votes[i] = binary.NativeEndian.Uint32(data[i*4:])
+	}
+	return h, nil
+}
+
+func (h *houghAccumulator) at(x, y, band int) uint32 {
+	return h.votes[(y*h.width+x)*h.bands+band]
+}
+
+// isPeak reports whether the cell is at least as large as its neighbours in x, y and band
+func (h *houghAccumulator) isPeak(x, y, band int) bool {
+	v := h.at(x, y, band)
+	for db := -1; db <= 1; db++ {
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				nx, ny, nb := x+dx, y+dy, band+db
+				if nx < 0 || ny < 0 || nb < 0 || nx >= h.width || ny >= h.height || nb >= h.bands {
+					continue
+				}
+				if h.at(nx, ny, nb) > v {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+func (h *houghAccumulator) peaks(n int) []houghPeak {
+	var peaks []houghPeak
+	for y := 0; y < h.height; y++ {
+		for x := 0; x < h.width; x++ {
+			for band := 0; band < h.bands; band++ {
+				if v := h.at(x, y, band); v > 0 && h.isPeak(x, y, band) {
+					peaks = append(peaks, houghPeak{x: x, y: y, band: band, votes: int(v)})
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(peaks, func(i, j int) bool {
+		return peaks[i].votes > peaks[j].votes
+	})
+	if n >= 0 && len(peaks) > n {
+		peaks = peaks[:n]
+	}
+	return peaks
+}
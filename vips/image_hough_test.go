@@ -0,0 +1,139 @@
+package vips
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newLineImage creates a one band black image with a white vertical line at x
+func newLineImage(t *testing.T, width, height, x int) *ImageRef {
+	img, err := Black(width, height)
+	require.NoError(t, err)
+	require.NoError(t, img.BandJoinConst([]float64{0, 0}))
+	require.NoError(t, img.DrawRect(ColorRGBA{R: 255, G: 255, B: 255, A: 255}, x, 0, 1, height, true))
+	require.NoError(t, img.ExtractBand(0, 1))
+	return img
+}
+
+func TestImageRef_EdgeDetectors(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	for name, op := range map[string]func(*ImageRef) error{
+		"canny":   func(img *ImageRef) error { return img.Canny(1.4, PrecisionFloat) },
+		"prewitt": func(img *ImageRef) error { return img.Prewitt() },
+		"scharr":  func(img *ImageRef) error { return img.Scharr() },
+	} {
+		t.Run(name, func(t *testing.T) {
+			img, err := NewImageFromFile(resources + "png-24bit.png")
+			require.NoError(t, err)
+			defer img.Close()
+
+			width, height := img.Width(), img.Height()
+			require.NoError(t, op(img))
+			assert.Equal(t, width, img.Width())
+			assert.Equal(t, height, img.Height())
+		})
+	}
+}
+
+func TestImageRef_HoughLine(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	img := newLineImage(t, 200, 200, 50)
+	defer img.Close()
+
+	acc, err := img.HoughLine(&HoughLineParams{Width: 180, Height: 200})
+	require.NoError(t, err)
+	defer acc.Close()
+	assert.Equal(t, 180, acc.Width())
+	assert.Equal(t, 200, acc.Height())
+
+	lines, err := acc.Peaks(1)
+	require.NoError(t, err)
+	require.Len(t, lines, 1)
+	assert.InDelta(t, 0, lines[0].Theta, 1)
+	assert.InDelta(t, 50, lines[0].Rho, 2)
+	assert.Greater(t, lines[0].Votes, 100)
+}
+
+func TestImageRef_HoughLine__NonSquare(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	// The line x + 2y = 300 across a 300x150 image, whose normal is at atan(2) from the x axis
+	gray := image.NewGray(image.Rect(0, 0, 300, 150))
+	for x := 2; x < 300; x++ {
+		gray.SetGray(x, (300-x)/2, color.Gray{Y: 255})
+	}
+	img, err := NewImageFromGoImage(gray)
+	require.NoError(t, err)
+	defer img.Close()
+	require.NoError(t, img.ExtractBand(0, 1))
+
+	acc, err := img.HoughLine(&HoughLineParams{Width: 180, Height: 200})
+	require.NoError(t, err)
+	defer acc.Close()
+
+	lines, err := acc.Peaks(1)
+	require.NoError(t, err)
+	require.Len(t, lines, 1)
+	assert.InDelta(t, 63.43, lines[0].Theta, 1.5)
+	assert.InDelta(t, 134.16, lines[0].Rho, 2)
+}
+
+func TestHoughLine(t *testing.T) {
+	// Square images keep the angle of the accumulator column and scale the distance by the size
+	line := houghLine(0, 50, 180, 200, 200, 200)
+	assert.InDelta(t, 0, line.Theta, 1e-9)
+	assert.InDelta(t, 50.5, line.Rho, 1e-9)
+
+	line = houghLine(90, 20, 180, 100, 300, 150)
+	assert.InDelta(t, 90, line.Theta, 1e-9)
+	assert.InDelta(t, 30.75, line.Rho, 1e-9)
+
+	// A normalised 45 degree line is steeper in pixels when the image is wider than tall
+	line = houghLine(45, 141, 180, 200, 300, 150)
+	assert.InDelta(t, 63.435, line.Theta, 1e-3)
+	assert.InDelta(t, 134.239, line.Rho, 1e-3)
+}
+
+func TestImageRef_HoughCircle(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	img := newLineImage(t, 120, 120, 60)
+	defer img.Close()
+
+	acc, err := img.HoughCircle(&HoughCircleParams{Scale: 2, MinRadius: 10, MaxRadius: 20})
+	require.NoError(t, err)
+	defer acc.Close()
+	assert.Equal(t, 60, acc.Width())
+
+	circles, err := acc.Peaks(3)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(circles), 3)
+	for _, c := range circles {
+		assert.GreaterOrEqual(t, c.R, 10)
+		assert.LessOrEqual(t, c.R, 20)
+	}
+
+	_, err = img.HoughCircle(&HoughCircleParams{MinRadius: 20, MaxRadius: 10})
+	assert.Error(t, err)
+}
+
+func TestHoughAccumulator_Peaks(t *testing.T) {
+	acc := &houghAccumulator{width: 4, height: 3, bands: 1, votes: []uint32{
+		0, 1, 0, 0,
+		1, 9, 2, 0,
+		0, 2, 3, 7,
+	}}
+
+	peaks := acc.peaks(5)
+	require.Len(t, peaks, 2)
+	assert.Equal(t, houghPeak{x: 1, y: 1, votes: 9}, peaks[0])
+	assert.Equal(t, houghPeak{x: 3, y: 2, votes: 7}, peaks[1])
+
+	assert.Len(t, acc.peaks(1), 1)
+}
@@ -8,6 +8,64 @@ import (
 	"strings"
 )
 
+// ProbeBuffer reads the metadata of an image buffer without decoding its pixels or creating an ImageRef.
+// It is subject to Config.AllowedLoaders like LoadImageFromBuffer.
+func ProbeBuffer(buf []byte) (*ImageMetadata, error) {
+	if err := startupIfNeeded(); err != nil {
+		return nil, err
+	}
+
+	vipsImage, _, format, err := vipsLoadFromBuffer(buf, NewImportParams())
+	if err != nil {
+		return nil, err
+	}
+	defer clearImage(vipsImage)
+
+	return vipsProbeMetadata(vipsImage, format), nil
+}
+
+// ProbeFile reads the metadata of an image file without decoding its pixels or creating an ImageRef.
+// It is subject to Config.AllowedLoaders like LoadImageFromFile.
+func ProbeFile(file string) (*ImageMetadata, error) {
+	if err := startupIfNeeded(); err != nil {
+		return nil, err
+	}
+
+	vipsImage, _, format, err := vipsLoadFromFile(file, NewImportParams())
+	if err != nil {
+		return nil, err
+	}
+	defer clearImage(vipsImage)
+
+	return vipsProbeMetadata(vipsImage, format), nil
+}
+
+func vipsProbeMetadata(in *C.VipsImage, format ImageType) *ImageMetadata {
+	pages := vipsGetImageNPages(in)
+	// JP2K reports pyramid layers as pages, see Pages()
+	if format == ImageTypeJP2K {
+		pages = 1
+	}
+
+	loader, _ := vipsImageGetMetaLoader(in)
+
+	return &ImageMetadata{
+		Format:        format,
+		Width:         int(in.Xsize),
+		Height:        int(in.Ysize),
+		Colorspace:    Interpretation(int(in.Type)),
+		Orientation:   vipsGetMetaOrientation(in),
+		Pages:         pages,
+		Bands:         int(in.Bands),
+		BandFormat:    BandFormat(int(in.BandFmt)),
+		PageHeight:    vipsGetPageHeight(in),
+		Loader:        loader,
+		HasICCProfile: vipsHasICCProfile(in),
+		HasExif:       vipsHasExif(in),
+		HasXMP:        vipsHasXMP(in),
+	}
+}
+
 // Format returns the current format of the vips image.
 func (r *ImageRef) Format() ImageType {
 	return r.format
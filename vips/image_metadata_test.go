@@ -0,0 +1,67 @@
+package vips
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbeBuffer(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	buf, err := os.ReadFile(resources + "jpg-24bit-icc-iec.jpg")
+	require.NoError(t, err)
+
+	before := openImageRefs.Load()
+	metadata, err := ProbeBuffer(buf)
+	require.NoError(t, err)
+	assert.Equal(t, before, openImageRefs.Load())
+
+	img, err := NewImageFromBuffer(buf)
+	require.NoError(t, err)
+	defer img.Close()
+
+	assert.Equal(t, ImageTypeJPEG, metadata.Format)
+	assert.Equal(t, img.Width(), metadata.Width)
+	assert.Equal(t, img.Height(), metadata.Height)
+	assert.Equal(t, img.Bands(), metadata.Bands)
+	assert.Equal(t, BandFormatUchar, metadata.BandFormat)
+	assert.Equal(t, img.Interpretation(), metadata.Colorspace)
+	assert.Equal(t, img.Orientation(), metadata.Orientation)
+	assert.Equal(t, 1, metadata.Pages)
+	assert.Equal(t, img.Height(), metadata.PageHeight)
+	assert.Equal(t, "jpegload_buffer", metadata.Loader)
+	assert.True(t, metadata.HasICCProfile)
+}
+
+func TestProbeFile(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	metadata, err := ProbeFile(resources + "gif-animated.gif")
+	require.NoError(t, err)
+
+	assert.Equal(t, ImageTypeGIF, metadata.Format)
+	assert.Greater(t, metadata.Pages, 1)
+	assert.Equal(t, metadata.Height, metadata.PageHeight)
+	assert.Equal(t, "gifload", metadata.Loader)
+	assert.False(t, metadata.HasExif)
+}
+
+func TestProbeFile__OriginalFormat(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	metadata, err := ProbeFile(resources + "bmp.bmp")
+	require.NoError(t, err)
+	assert.Equal(t, ImageTypeBMP, metadata.Format)
+}
+
+func TestProbeBuffer__Unsupported(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	metadata, err := ProbeBuffer([]byte("definitely not an image file"))
+	assert.Nil(t, metadata)
+	assert.True(t, errors.Is(err, ErrUnsupportedImageFormat))
+}
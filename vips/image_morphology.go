@@ -0,0 +1,154 @@
+package vips
+
+// #include "image.h"
+import "C"
+
+import (
+	"errors"
+	"runtime"
+)
+
+// Mask values understood by the libvips morphology operations
+const (
+	MaskClear    = 0
+	MaskDontCare = 128
+	MaskSet      = 255
+)
+
+// Mask is a structuring element for morphological operations, stored row by row.
+// Each value is MaskSet, MaskClear or MaskDontCare.
+type Mask struct {
+	Width  int
+	Height int
+	Values []float64
+}
+
+// NewMask creates a Mask from rows of equal length
+func NewMask(rows [][]float64) (*Mask, error) {
+	if len(rows) == 0 || len(rows[0]) == 0 {
+		return nil, errors.New("mask must not be empty")
+	}
+
+	m := &Mask{Width: len(rows[0]), Height: len(rows)}
+	for _, row := range rows {
+		if len(row) != m.Width {
+			return nil, errors.New("mask rows must have equal length")
+		}
+		m.Values = append(m.Values, row...)
+	}
+	return m, nil
+}
+
+// NewSquareMask creates a size by size Mask with every element set
+func NewSquareMask(size int) *Mask {
+	m := &Mask{Width: size, Height: size, Values: make([]float64, size*size)}
+	for i := range m.Values {
+		m.Values[i] = MaskSet
+	}
+	return m
+}
+
+// NewCrossMask creates a size by size Mask with the centre row and column set
+func NewCrossMask(size int) *Mask {
+	m := &Mask{Width: size, Height: size, Values: make([]float64, size*size)}
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if x == size/2 || y == size/2 {
+				m.Values[y*size+x] = MaskSet
+			} else {
+				m.Values[y*size+x] = MaskDontCare
+			}
+		}
+	}
+	return m
+}
+
+// NewDiskMask creates a Mask with the elements within radius of the centre set
+func NewDiskMask(radius int) *Mask {
+	size := 2*radius + 1
+	m := &Mask{Width: size, Height: size, Values: make([]float64, size*size)}
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			dx, dy := x-radius, y-radius
+			if dx*dx+dy*dy <= radius*radius {
+				m.Values[y*size+x] = MaskSet
+			} else {
+				m.Values[y*size+x] = MaskDontCare
+			}
+		}
+	}
+	return m
+}
+
+// Erode shrinks the set regions of a one band uchar image, such as one produced by thresholding,
+// using mask as the structuring element.
+func (r *ImageRef) Erode(mask *Mask) error {
+	return r.morph(mask, OperationMorphologyErode)
+}
+
+// Dilate grows the set regions of a one band uchar image using mask as the structuring element.
+func (r *ImageRef) Dilate(mask *Mask) error {
+	return r.morph(mask, OperationMorphologyDilate)
+}
+
+// Opening erodes then dilates the image with mask, removing specks smaller than the mask.
+func (r *ImageRef) Opening(mask *Mask) error {
+	if err := r.Erode(mask); err != nil {
+		return err
+	}
+	return r.Dilate(mask)
+}
+
+// Closing dilates then erodes the image with mask, filling holes smaller than the mask.
+func (r *ImageRef) Closing(mask *Mask) error {
+	if err := r.Dilate(mask); err != nil {
+		return err
+	}
+	return r.Erode(mask)
+}
+
+// Median replaces each pixel by the median of the size by size window around it.
+func (r *ImageRef) Median(size int) error {
+	return r.Rank(size, size, size*size/2)
+}
+
+// LabelRegions labels the connected regions of equal pixel value. It returns a new
+// one band int image with every pixel set to the number of its region, and the
+// number of regions found.
+func (r *ImageRef) LabelRegions() (*ImageRef, int, error) {
+	defer runtime.KeepAlive(r)
+	out, segments, err := vipsGenLabelregions(r.image)
+	if err != nil {
+		return nil, 0, err
+	}
+	return newImageRef(out, r.format, r.originalFormat, nil), segments, nil
+}
+
+func (r *ImageRef) morph(mask *Mask, morph OperationMorphology) error {
+	defer runtime.KeepAlive(r)
+	maskImage, err := vipsMatrixImage(mask.Width, mask.Height, mask.Values)
+	if err != nil {
+		return err
+	}
+	defer clearImage(maskImage)
+
+	out, err := vipsGenMorph(r.image, maskImage, morph)
+	if err != nil {
+		return err
+	}
+	r.setImage(out)
+	return nil
+}
+
+// vipsMatrixImage creates a libvips matrix image from row-major values
+func vipsMatrixImage(width, height int, values []float64) (*C.VipsImage, error) {
+	if width <= 0 || height <= 0 || len(values) != width*height {
+		return nil, errors.New("matrix size does not match its values")
+	}
+
+	out := C.vips_image_new_matrix_from_array(C.int(width), C.int(height), (*C.double)(&values[0]), C.int(len(values)))
+	if out == nil {
+		return nil, handleVipsError()
+	}
+	return out, nil
+}
@@ -0,0 +1,95 @@
+package vips
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loadBinaryImage loads a test image as a one band uchar image of 0 and 255
+func loadBinaryImage(t *testing.T) *ImageRef {
+	img, err := NewImageFromFile(resources + "png-24bit.png")
+	require.NoError(t, err)
+	require.NoError(t, img.ToColorSpace(InterpretationBW))
+	require.NoError(t, img.ExtractBand(0, 1))
+	require.NoError(t, img.Linear([]float64{255}, []float64{-128 * 255}))
+	require.NoError(t, img.Cast(BandFormatUchar))
+	return img
+}
+
+func TestNewMask(t *testing.T) {
+	m, err := NewMask([][]float64{
+		{MaskDontCare, MaskSet, MaskDontCare},
+		{MaskSet, MaskSet, MaskSet},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, m.Width)
+	assert.Equal(t, 2, m.Height)
+	assert.Equal(t, []float64{128, 255, 128, 255, 255, 255}, m.Values)
+
+	_, err = NewMask([][]float64{{255, 255}, {255}})
+	assert.Error(t, err)
+
+	_, err = NewMask(nil)
+	assert.Error(t, err)
+
+	disk := NewDiskMask(1)
+	assert.Equal(t, NewCrossMask(3).Values, disk.Values)
+}
+
+func TestImageRef_Morphology(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	for name, op := range map[string]func(*ImageRef) error{
+		"erode":   func(img *ImageRef) error { return img.Erode(NewSquareMask(3)) },
+		"dilate":  func(img *ImageRef) error { return img.Dilate(NewCrossMask(3)) },
+		"opening": func(img *ImageRef) error { return img.Opening(NewDiskMask(2)) },
+		"closing": func(img *ImageRef) error { return img.Closing(NewDiskMask(2)) },
+		"median":  func(img *ImageRef) error { return img.Median(3) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			img := loadBinaryImage(t)
+			defer img.Close()
+
+			width, height := img.Width(), img.Height()
+			require.NoError(t, op(img))
+			assert.Equal(t, width, img.Width())
+			assert.Equal(t, height, img.Height())
+
+			_, _, err := img.ExportPng(nil)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestImageRef_ErodeShrinksRegions(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	img := loadBinaryImage(t)
+	defer img.Close()
+
+	before, err := img.Average()
+	require.NoError(t, err)
+
+	require.NoError(t, img.Erode(NewSquareMask(5)))
+
+	after, err := img.Average()
+	require.NoError(t, err)
+	assert.True(t, after <= before)
+}
+
+func TestImageRef_LabelRegions(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	img := loadBinaryImage(t)
+	defer img.Close()
+
+	labels, count, err := img.LabelRegions()
+	require.NoError(t, err)
+	defer labels.Close()
+
+	assert.Greater(t, count, 0)
+	assert.Equal(t, img.Width(), labels.Width())
+	assert.Equal(t, BandFormatInt, labels.BandFormat())
+}
@@ -0,0 +1,131 @@
+package vips
+
+import (
+	"image"
+	"runtime"
+)
+
+// MosaicParams are the optional parameters of Mosaic. Zero values use the libvips defaults.
+type MosaicParams struct {
+	// HalfWindow is the half size of the window correlated around each tie point
+	HalfWindow int
+	// HalfArea is the half size of the area searched for the best match
+	HalfArea int
+	// MaxBlend is the widest seam blended between the images. Zero uses the libvips
+	// default and negative disables blending.
+	MaxBlend int
+	// Band is the band used to find the overlap
+	Band int
+}
+
+// MosaicMatch describes how the secondary image was placed by Mosaic
+type MosaicMatch struct {
+	// DX and DY are the integer offset found between the images, as passed to Merge
+	DX int
+	DY int
+	// Scale, Angle, DX1 and DY1 are the first-order transform detected between the images
+	Scale float64
+	Angle float64
+	DX1   float64
+	DY1   float64
+}
+
+// Mosaic joins other onto the image in direction, finding their overlap automatically.
+// refPoint is a feature in this image and secPoint the same feature in other; the
+// overlap is searched for around them and the seam is blended. It returns the placement
+// found, which can be reused with Merge for further tiles with the same layout.
+func (r *ImageRef) Mosaic(other *ImageRef, direction Direction, refPoint, secPoint image.Point, params *MosaicParams) (*MosaicMatch, error) {
+	defer runtime.KeepAlive(r)
+	defer runtime.KeepAlive(other)
+
+	opts := &MosaicOptions{}
+	if params != nil {
+		if params.HalfWindow > 0 {
+			opts.Hwindow = &params.HalfWindow
+		}
+		if params.HalfArea > 0 {
+			opts.Harea = &params.HalfArea
+		}
+		opts.Mblend = maxBlendOption(params.MaxBlend)
+		if params.Band > 0 {
+			opts.Bandno = &params.Band
+		}
+	}
+
+	out, dx, dy, scale, angle, dy1, dx1, err := vipsGenMosaic(r.image, other.image, direction,
+		refPoint.X, refPoint.Y, secPoint.X, secPoint.Y, opts)
+	if err != nil {
+		return nil, err
+	}
+	r.setImage(out)
+
+	return &MosaicMatch{DX: dx, DY: dy, Scale: scale, Angle: angle, DX1: dx1, DY1: dy1}, nil
+}
+
+// MergeParams are the optional parameters of Merge. Zero values use the libvips defaults.
+type MergeParams struct {
+	// MaxBlend is the widest seam blended between the images. Zero uses the libvips
+	// default and negative disables blending.
+	MaxBlend int
+}
+
+// Merge joins other onto the image in direction at a known offset and blends the seam.
+// dx and dy are the displacement from other to this image, so a secondary image starting
+// 100 pixels to the right of this one has a dx of -100.
+func (r *ImageRef) Merge(other *ImageRef, direction Direction, dx, dy int, params *MergeParams) error {
+	defer runtime.KeepAlive(r)
+	defer runtime.KeepAlive(other)
+
+	opts := &MergeOptions{}
+	if params != nil {
+		opts.Mblend = maxBlendOption(params.MaxBlend)
+	}
+
+	out, err := vipsGenMerge(r.image, other.image, direction, dx, dy, opts)
+	if err != nil {
+		return err
+	}
+	r.setImage(out)
+	return nil
+}
+
+// maxBlendOption maps a MaxBlend parameter to the mblend option of libvips, which takes 0
+// to disable blending
+func maxBlendOption(maxBlend int) *int {
+	if maxBlend == 0 {
+		return nil
+	}
+	blend := max(maxBlend, 0)
+	return &blend
+}
+
+// GlobalBalanceParams are the optional parameters of GlobalBalance
+type GlobalBalanceParams struct {
+	// Gamma of the image sensor, 1.6 when zero
+	Gamma float64
+	// IntOutput keeps the input band format rather than producing a float image
+	IntOutput bool
+}
+
+// GlobalBalance evens out the brightness of the tiles of a mosaic assembled with Mosaic and
+// Merge, so the seams between tiles shot under different exposure disappear. libvips finds
+// the tiles from the mosaic's history, so they must have been loaded from files which are
+// still available.
+func (r *ImageRef) GlobalBalance(params *GlobalBalanceParams) error {
+	defer runtime.KeepAlive(r)
+
+	opts := &GlobalbalanceOptions{}
+	if params != nil {
+		if params.Gamma > 0 {
+			opts.Gamma = &params.Gamma
+		}
+		opts.IntOutput = &params.IntOutput
+	}
+
+	out, err := vipsGenGlobalbalance(r.image, opts)
+	if err != nil {
+		return err
+	}
+	r.setImage(out)
+	return nil
+}
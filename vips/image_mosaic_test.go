@@ -0,0 +1,112 @@
+package vips
+
+import (
+	"image"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// overlappingTiles cuts the test image into left and right tiles which overlap by
+// overlap pixels. It returns the tiles and the x position of the right tile.
+func overlappingTiles(t *testing.T, overlap int) (*ImageRef, *ImageRef, int) {
+	img, err := NewImageFromFile(resources + "png-24bit.png")
+	require.NoError(t, err)
+	defer img.Close()
+
+	width, height := img.Width(), img.Height()
+	split := width / 2
+
+	left, err := img.Copy()
+	require.NoError(t, err)
+	require.NoError(t, left.ExtractArea(0, 0, split+overlap/2, height))
+
+	right, err := img.Copy()
+	require.NoError(t, err)
+	require.NoError(t, right.ExtractArea(split-overlap/2, 0, width-split+overlap/2, height))
+
+	return left, right, split - overlap/2
+}
+
+func TestImageRef_Merge(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	left, right, x := overlappingTiles(t, 200)
+	defer left.Close()
+	defer right.Close()
+
+	err := left.Merge(right, DirectionHorizontal, -x, 0, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1920, left.Width())
+	assert.Equal(t, 1080, left.Height())
+}
+
+func TestImageRef_Merge__NoBlend(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	left, right, x := overlappingTiles(t, 200)
+	defer left.Close()
+	defer right.Close()
+
+	require.NoError(t, left.Merge(right, DirectionHorizontal, -x, 0, &MergeParams{MaxBlend: -1}))
+	assert.Equal(t, 1920, left.Width())
+}
+
+func TestMaxBlendOption(t *testing.T) {
+	assert.Nil(t, maxBlendOption(0))
+	assert.Equal(t, 0, *maxBlendOption(-1))
+	assert.Equal(t, 25, *maxBlendOption(25))
+}
+
+func TestImageRef_Mosaic(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	left, right, x := overlappingTiles(t, 200)
+	defer left.Close()
+	defer right.Close()
+
+	// The same feature in both tiles, deliberately a few pixels off in the secondary
+	refPoint := image.Pt(x+100, 540)
+	secPoint := image.Pt(100+3, 540-2)
+
+	match, err := left.Mosaic(right, DirectionHorizontal, refPoint, secPoint, &MosaicParams{HalfArea: 20})
+	require.NoError(t, err)
+
+	assert.Equal(t, -x, match.DX)
+	assert.Equal(t, 0, match.DY)
+	assert.Equal(t, 1920, left.Width())
+}
+
+func TestImageRef_GlobalBalance(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	// Global balance reloads the tiles from the files named in the mosaic history
+	dir := t.TempDir()
+	leftPath := filepath.Join(dir, "left.png")
+	rightPath := filepath.Join(dir, "right.png")
+
+	left, right, x := overlappingTiles(t, 200)
+	_, err := left.SaveToFile(leftPath, nil)
+	require.NoError(t, err)
+	_, err = right.SaveToFile(rightPath, nil)
+	require.NoError(t, err)
+	left.Close()
+	right.Close()
+
+	left, err = NewImageFromFile(leftPath)
+	require.NoError(t, err)
+	defer left.Close()
+	right, err = NewImageFromFile(rightPath)
+	require.NoError(t, err)
+	defer right.Close()
+
+	require.NoError(t, left.Merge(right, DirectionHorizontal, -x, 0, nil))
+
+	err = left.GlobalBalance(&GlobalBalanceParams{IntOutput: true})
+	require.NoError(t, err)
+	assert.Equal(t, 1920, left.Width())
+	assert.Equal(t, BandFormatUchar, left.BandFormat())
+}
@@ -52,6 +52,40 @@ func (r *ImageRef) Sobel() error {
 	return nil
 }
 
+// Prewitt applies the Prewitt edge detector to the image.
+func (r *ImageRef) Prewitt() error {
+	defer runtime.KeepAlive(r)
+	out, err := vipsGenPrewitt(r.image)
+	if err != nil {
+		return err
+	}
+	r.setImage(out)
+	return nil
+}
+
+// Scharr applies the Scharr edge detector to the image.
+func (r *ImageRef) Scharr() error {
+	defer runtime.KeepAlive(r)
+	out, err := vipsGenScharr(r.image)
+	if err != nil {
+		return err
+	}
+	r.setImage(out)
+	return nil
+}
+
+// Canny applies the Canny edge detector to the image. sigma is the size of the Gaussian
+// blur applied first and precision the precision of the computation.
+func (r *ImageRef) Canny(sigma float64, precision Precision) error {
+	defer runtime.KeepAlive(r)
+	out, err := vipsGenCanny(r.image, &CannyOptions{Sigma: &sigma, Precision: &precision})
+	if err != nil {
+		return err
+	}
+	r.setImage(out)
+	return nil
+}
+
 // Rank does rank filtering on an image. A window of size width by height is passed over the image.
 // At each position, the pixels inside the window are sorted into ascending order and the pixel at position
 // index is output. index numbers from 0.
@@ -67,8 +101,16 @@ func (r *ImageRef) Rank(width int, height int, index int) error {
 
 // Mapim resamples an image using index to look up pixels
 func (r *ImageRef) Mapim(index *ImageRef) error {
+	return r.MapimWithInterpolator(index, nil)
+}
+
+// MapimWithInterpolator is like Mapim but resamples with interpolator. A nil interpolator
+// uses bilinear interpolation.
+func (r *ImageRef) MapimWithInterpolator(index *ImageRef, interpolator *Interpolator) error {
 	defer runtime.KeepAlive(r)
-	out, err := vipsGenMapim(r.image, index.image, nil)
+	defer runtime.KeepAlive(index)
+	defer runtime.KeepAlive(interpolator)
+	out, err := vipsGenMapim(r.image, index.image, &MapimOptions{Interpolate: interpolator.vipsInterpolate()})
 	if err != nil {
 		return err
 	}
@@ -2022,3 +2022,76 @@ func TestExportNative_MoreFormats(t *testing.T) {
 		assert.Equal(t, ImageTypeHEIF, meta.Format)
 	})
 }
+
+func TestImageRef_RotateArbitrary(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	image, err := NewImageFromFile(resources + "png-24bit.png")
+	require.NoError(t, err)
+	defer image.Close()
+
+	width, height := image.Width(), image.Height()
+
+	interpolator, err := NewInterpolator(InterpolatorBicubic)
+	require.NoError(t, err)
+	defer interpolator.Close()
+
+	err = image.RotateArbitrary(1.5, &ColorRGBA{R: 255, G: 255, B: 255, A: 255}, interpolator)
+	require.NoError(t, err)
+
+	assert.Greater(t, image.Width(), width)
+	assert.Greater(t, image.Height(), height)
+}
+
+func TestImageRef_RotateArbitrary__MultiPage(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	image, err := NewImageFromFile(resources + "gif-animated.gif")
+	require.NoError(t, err)
+	defer image.Close()
+
+	pages := image.Pages()
+	pageHeight := image.PageHeight()
+
+	err = image.RotateArbitrary(10, nil, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, pages, image.Pages())
+	assert.Greater(t, image.PageHeight(), pageHeight)
+	assert.Equal(t, pages*image.PageHeight(), image.Height())
+}
+
+func TestImageRef_Affine(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	image, err := NewImageFromFile(resources + "png-24bit.png")
+	require.NoError(t, err)
+	defer image.Close()
+
+	width, height := image.Width(), image.Height()
+
+	err = image.Affine([4]float64{2, 0, 0, 2}, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, width*2, image.Width())
+	assert.Equal(t, height*2, image.Height())
+}
+
+func TestImageRef_Affine__OutputArea(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	img, err := NewImageFromFile(resources + "png-24bit.png")
+	require.NoError(t, err)
+	defer img.Close()
+
+	area := image.Rect(10, 20, 110, 70)
+	err = img.Affine([4]float64{1, 0.2, 0, 1}, &AffineParams{
+		OutputArea: &area,
+		Extend:     ExtendBackground,
+		Background: &ColorRGBA{R: 255, A: 255},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 100, img.Width())
+	assert.Equal(t, 50, img.Height())
+}
@@ -5,6 +5,7 @@ import "C"
 
 import (
 	"errors"
+	"image"
 	"math"
 	"runtime"
 	"unsafe"
@@ -101,6 +102,32 @@ func (r *ImageRef) ResizeWithVScale(hScale, vScale float64, kernel Kernel) error
 	return r.UnpremultiplyAlpha()
 }
 
+// ResizeWithInterpolator resizes the image by the given scaling factors, resampling with
+// interpolator rather than a kernel. A vScale of -1 uses hScale for both axes. This suits
+// enlarging, for example with InterpolatorNearest for pixel art; for reducing, Resize gives
+// better quality.
+func (r *ImageRef) ResizeWithInterpolator(hScale, vScale float64, interpolator *Interpolator) error {
+	defer runtime.KeepAlive(r)
+	if vScale == -1 {
+		vScale = hScale
+	}
+
+	if err := r.PremultiplyAlpha(); err != nil {
+		return err
+	}
+
+	params := &AffineParams{
+		Interpolator:  interpolator,
+		Extend:        ExtendCopy,
+		Premultiplied: true,
+	}
+	if err := r.Affine([4]float64{hScale, 0, 0, vScale}, params); err != nil {
+		return err
+	}
+
+	return r.UnpremultiplyAlpha()
+}
+
 // Thumbnail resizes the image to the given width and height.
 // crop decides algorithm vips uses to shrink and crop to fill target,
 func (r *ImageRef) Thumbnail(width, height int, crop Interesting) error {
@@ -309,6 +336,92 @@ func (r *ImageRef) Rotate(angle Angle) error {
 	return nil
 }
 
+// RotateArbitrary rotates the image clockwise by any number of degrees, such as a small
+// fraction to deskew a scan. The output is enlarged to hold the rotated image and new
+// pixels are filled with background, or black when it is nil. A nil interpolator uses
+// bilinear interpolation. Each page of a multi-page image is rotated separately.
+func (r *ImageRef) RotateArbitrary(degrees float64, background *ColorRGBA, interpolator *Interpolator) error {
+	defer runtime.KeepAlive(r)
+	defer runtime.KeepAlive(interpolator)
+	rotate := func(in *C.VipsImage) (*C.VipsImage, error) {
+		return vipsGenRotate(in, degrees, &RotateOptions{
+			Interpolate: interpolator.vipsInterpolate(),
+			Background:  vipsBackground(in, background),
+		})
+	}
+
+	out, err := r.transformPages(rotate)
+	if err != nil {
+		return err
+	}
+	r.setImage(out)
+	return nil
+}
+
+// AffineParams are the optional parameters of Affine
+type AffineParams struct {
+	// Interpolator used to resample the image, bilinear when nil
+	Interpolator *Interpolator
+	// OutputArea is the area of the transformed space to output, the bounding box of the
+	// transformed image when nil
+	OutputArea *image.Rectangle
+	// OutputOffsetX and OutputOffsetY displace the output
+	OutputOffsetX float64
+	OutputOffsetY float64
+	// InputOffsetX and InputOffsetY displace the input before transforming
+	InputOffsetX float64
+	InputOffsetY float64
+	// Extend decides how pixels outside the input are generated
+	Extend ExtendStrategy
+	// Background fills pixels outside the input when Extend is ExtendBackground
+	Background *ColorRGBA
+	// Premultiplied skips premultiplying the alpha band because the image already is
+	Premultiplied bool
+}
+
+// Affine transforms the image by the matrix [a, b, c, d], mapping each input pixel (x, y)
+// to (a*x + b*y, c*x + d*y) plus the offsets in params. Each page of a multi-page image
+// is transformed separately.
+func (r *ImageRef) Affine(matrix [4]float64, params *AffineParams) error {
+	defer runtime.KeepAlive(r)
+	if params == nil {
+		params = &AffineParams{}
+	}
+	defer runtime.KeepAlive(params.Interpolator)
+
+	affine := func(in *C.VipsImage) (*C.VipsImage, error) {
+		opts := &AffineOptions{
+			Interpolate:   params.Interpolator.vipsInterpolate(),
+			Odx:           &params.OutputOffsetX,
+			Ody:           &params.OutputOffsetY,
+			Idx:           &params.InputOffsetX,
+			Idy:           &params.InputOffsetY,
+			Extend:        &params.Extend,
+			Background:    vipsBackground(in, params.Background),
+			Premultiplied: &params.Premultiplied,
+		}
+		if area := params.OutputArea; area != nil {
+			opts.Oarea = []int{area.Min.X, area.Min.Y, area.Dx(), area.Dy()}
+		}
+		return vipsGenAffine(in, matrix[:], opts)
+	}
+
+	out, err := r.transformPages(affine)
+	if err != nil {
+		return err
+	}
+	r.setImage(out)
+	return nil
+}
+
+// transformPages applies a geometric transform to the image, page by page if it has several
+func (r *ImageRef) transformPages(fn func(in *C.VipsImage) (*C.VipsImage, error)) (*C.VipsImage, error) {
+	if r.Pages() > 1 {
+		return vipsMapPages(r.image, fn)
+	}
+	return fn(r.image)
+}
+
 // Similarity lets you scale, offset and rotate images by arbitrary angles in a single operation while defining the
 // color of new background pixels. If the input image has no alpha channel, the alpha on `backgroundColor` will be
 // ignored. You can add an alpha channel to an image with `BandJoinConst` (e.g. `img.BandJoinConst([]float64{255})`) or
@@ -324,6 +437,29 @@ func (r *ImageRef) Similarity(scale float64, angle float64, backgroundColor *Col
 	return nil
 }
 
+// SimilarityWithInterpolator is like Similarity but resamples with interpolator. A nil
+// interpolator uses bilinear interpolation.
+func (r *ImageRef) SimilarityWithInterpolator(scale float64, angle float64, backgroundColor *ColorRGBA,
+	idx float64, idy float64, odx float64, ody float64, interpolator *Interpolator) error {
+	defer runtime.KeepAlive(r)
+	defer runtime.KeepAlive(interpolator)
+	out, err := vipsGenSimilarity(r.image, &SimilarityOptions{
+		Scale:       &scale,
+		Angle:       &angle,
+		Interpolate: interpolator.vipsInterpolate(),
+		Background:  vipsBackground(r.image, backgroundColor),
+		Idx:         &idx,
+		Idy:         &idy,
+		Odx:         &odx,
+		Ody:         &ody,
+	})
+	if err != nil {
+		return err
+	}
+	r.setImage(out)
+	return nil
+}
+
 // Grid tiles the image pages into a matrix across*down
 func (r *ImageRef) Grid(tileHeight, across, down int) error {
 	defer runtime.KeepAlive(r)
@@ -3,12 +3,32 @@ package vips
 // #include <vips/vips.h>
 import "C"
 
-import "unsafe"
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"unsafe"
+)
+
+// InterpolatorKind names a libvips interpolator
+type InterpolatorKind string
+
+// InterpolatorKind enum
+const (
+	InterpolatorNearest  InterpolatorKind = "nearest"
+	InterpolatorBilinear InterpolatorKind = "bilinear"
+	InterpolatorBicubic  InterpolatorKind = "bicubic"
+	InterpolatorLBB      InterpolatorKind = "lbb"
+	InterpolatorNohalo   InterpolatorKind = "nohalo"
+	InterpolatorVSQBS    InterpolatorKind = "vsqbs"
+)
 
 // NewInterpolate creates a VipsInterpolate from a name string.
 // Common names: "nearest", "bilinear", "bicubic", "nohalo", "vsqbs", "lbb".
 // The caller should call g_object_unref on the result when done, or pass it
 // to a generated operation (which does not take ownership).
+//
+// Deprecated: Use NewInterpolator, which releases the interpolator automatically.
 func NewInterpolate(name string) (*C.VipsInterpolate, error) {
 	cName := C.CString(name)
 	defer C.free(unsafe.Pointer(cName))
@@ -18,3 +38,61 @@ func NewInterpolate(name string) (*C.VipsInterpolate, error) {
 	}
 	return interp, nil
 }
+
+// Interpolator is a libvips interpolator used when resampling images, for example by
+// Affine, RotateArbitrary, ResizeWithInterpolator, SimilarityWithInterpolator and
+// MapimWithInterpolator.
+// Interpolators are released by the GC; call Close to release one sooner.
+type Interpolator struct {
+	kind   InterpolatorKind
+	lock   sync.Mutex
+	interp *C.VipsInterpolate
+}
+
+// NewInterpolator creates an Interpolator of the given kind
+func NewInterpolator(kind InterpolatorKind) (*Interpolator, error) {
+	interp, err := NewInterpolate(string(kind))
+	if err != nil {
+		return nil, err
+	}
+
+	i := &Interpolator{kind: kind, interp: interp}
+	runtime.SetFinalizer(i, finalizeInterpolator)
+	return i, nil
+}
+
+func finalizeInterpolator(i *Interpolator) {
+	govipsLog("govips", LogLevelDebug, fmt.Sprintf("closing interpolator %p", i))
+	i.Close()
+}
+
+// Kind returns the kind of the interpolator
+func (i *Interpolator) Kind() InterpolatorKind {
+	return i.kind
+}
+
+// Close releases the interpolator. Calling Close is optional and it is safe to call more than once.
+func (i *Interpolator) Close() {
+	if i == nil {
+		return
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if i.interp != nil {
+		C.g_object_unref(C.gpointer(i.interp))
+		i.interp = nil
+	}
+}
+
+// vipsInterpolate returns the underlying interpolator, or nil to use the libvips default
+func (i *Interpolator) vipsInterpolate() *C.VipsInterpolate {
+	if i == nil {
+		return nil
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	return i.interp
+}
@@ -0,0 +1,100 @@
+package vips
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewInterpolator(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	for _, kind := range []InterpolatorKind{
+		InterpolatorNearest,
+		InterpolatorBilinear,
+		InterpolatorBicubic,
+		InterpolatorLBB,
+		InterpolatorNohalo,
+		InterpolatorVSQBS,
+	} {
+		t.Run(string(kind), func(t *testing.T) {
+			interpolator, err := NewInterpolator(kind)
+			require.NoError(t, err)
+			assert.Equal(t, kind, interpolator.Kind())
+			assert.NotNil(t, interpolator.vipsInterpolate())
+
+			interpolator.Close()
+			assert.Nil(t, interpolator.vipsInterpolate())
+
+			// Closing twice is safe
+			interpolator.Close()
+		})
+	}
+
+	_, err := NewInterpolator("unknown")
+	assert.Error(t, err)
+}
+
+func TestImageRef_ResizeWithInterpolator(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	image, err := NewImageFromFile(resources + "png-8bit+alpha.png")
+	require.NoError(t, err)
+	defer image.Close()
+
+	width, height := image.Width(), image.Height()
+
+	interpolator, err := NewInterpolator(InterpolatorNearest)
+	require.NoError(t, err)
+	defer interpolator.Close()
+
+	err = image.ResizeWithInterpolator(2, -1, interpolator)
+	require.NoError(t, err)
+
+	assert.Equal(t, width*2, image.Width())
+	assert.Equal(t, height*2, image.Height())
+	assert.True(t, image.HasAlpha())
+}
+
+func TestImageRef_SimilarityWithInterpolator(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	image, err := NewImageFromFile(resources + "png-24bit.png")
+	require.NoError(t, err)
+	defer image.Close()
+
+	width := image.Width()
+
+	interpolator, err := NewInterpolator(InterpolatorNohalo)
+	require.NoError(t, err)
+	defer interpolator.Close()
+
+	err = image.SimilarityWithInterpolator(0.5, 0, &ColorRGBA{}, 0, 0, 0, 0, interpolator)
+	require.NoError(t, err)
+
+	assert.InDelta(t, width/2, image.Width(), 1)
+}
+
+func TestImageRef_MapimWithInterpolator(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	image, err := NewImageFromFile(resources + "png-24bit.png")
+	require.NoError(t, err)
+	defer image.Close()
+
+	index, err := XYZ(image.Width(), image.Height())
+	require.NoError(t, err)
+	defer index.Close()
+
+	interpolator, err := NewInterpolator(InterpolatorBicubic)
+	require.NoError(t, err)
+	defer interpolator.Close()
+
+	width, height := image.Width(), image.Height()
+	err = image.MapimWithInterpolator(index, interpolator)
+	require.NoError(t, err)
+
+	assert.Equal(t, width, image.Width())
+	assert.Equal(t, height, image.Height())
+}
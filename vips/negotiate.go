@@ -0,0 +1,172 @@
+package vips
+
+import (
+	"strconv"
+	"strings"
+)
+
+// DefaultNegotiationPreference is the order in which NegotiateFormat tries output formats
+// when NegotiationPolicy.Preferred is empty: the most compact formats first.
+var DefaultNegotiationPreference = []ImageType{
+	ImageTypeAVIF,
+	ImageTypeWEBP,
+	ImageTypeJPEG,
+	ImageTypePNG,
+	ImageTypeGIF,
+}
+
+// Formats which can store an alpha channel or several pages when exported by govips
+var (
+	alphaImageTypes = map[ImageType]bool{
+		ImageTypePNG:  true,
+		ImageTypeWEBP: true,
+		ImageTypeAVIF: true,
+		ImageTypeHEIF: true,
+		ImageTypeGIF:  true,
+		ImageTypeTIFF: true,
+		ImageTypeJP2K: true,
+		ImageTypeJXL:  true,
+	}
+	animatedImageTypes = map[ImageType]bool{
+		ImageTypeWEBP: true,
+		ImageTypeGIF:  true,
+	}
+	// wildcardImageTypes may be picked through a wildcard such as */* or image/*, which
+	// clients like curl and crawlers send without being able to decode newer formats
+	wildcardImageTypes = map[ImageType]bool{
+		ImageTypeJPEG: true,
+		ImageTypePNG:  true,
+		ImageTypeGIF:  true,
+	}
+)
+
+// NegotiationPolicy controls how NegotiateFormat picks an output format
+type NegotiationPolicy struct {
+	// Preferred lists the candidate formats, most preferred first. Defaults to
+	// DefaultNegotiationPreference.
+	Preferred []ImageType
+	// Fallback is returned when the client accepts none of the candidates. Defaults to
+	// JPEG, or PNG for images with alpha, or GIF for animated images.
+	Fallback ImageType
+	// IgnoreAlpha allows formats without alpha support for images with an alpha channel,
+	// which are then flattened on export
+	IgnoreAlpha bool
+	// IgnoreAnimation allows formats without animation support for animated images, which
+	// then keep only their first page
+	IgnoreAnimation bool
+}
+
+// acceptEntry is one media range from an Accept header
+type acceptEntry struct {
+	mimeType string
+	q        float64
+}
+
+// NegotiateFormat picks the output format for img from an HTTP Accept header. Candidates are
+// the policy's preferred formats which this build of libvips can save and which can keep the
+// image's alpha channel and animation. Formats the client names explicitly rank above those
+// only matched by a wildcard, which can only pick JPEG, PNG or GIF. Within each rank the highest
+// quality value wins, with ties going to the more preferred format. It returns the format and
+// its media type, suitable for a Content-Type header. img may be nil.
+func NegotiateFormat(accept string, img *ImageRef, policy NegotiationPolicy) (ImageType, string) {
+	hasAlpha, animated := false, false
+	if img != nil {
+		hasAlpha = img.HasAlpha() && !policy.IgnoreAlpha
+		animated = img.Pages() > 1 && !policy.IgnoreAnimation
+	}
+
+	preferred := policy.Preferred
+	if len(preferred) == 0 {
+		preferred = DefaultNegotiationPreference
+	}
+
+	entries := parseAccept(accept)
+	best, bestQ, bestExplicit := ImageTypeUnknown, 0.0, false
+	for _, imageType := range preferred {
+		if !IsSaveTypeSupported(imageType) ||
+			(hasAlpha && !alphaImageTypes[imageType]) ||
+			(animated && !animatedImageTypes[imageType]) {
+			continue
+		}
+
+		q, explicit := acceptQuality(entries, imageType.MIMEType())
+		if q == 0 || (!explicit && !wildcardImageTypes[imageType]) {
+			continue
+		}
+		if (explicit && !bestExplicit) || (explicit == bestExplicit && q > bestQ) {
+			best, bestQ, bestExplicit = imageType, q, explicit
+		}
+	}
+
+	if best == ImageTypeUnknown {
+		best = policy.Fallback
+		if best == ImageTypeUnknown {
+			switch {
+			case animated:
+				best = ImageTypeGIF
+			case hasAlpha:
+				best = ImageTypePNG
+			default:
+				best = ImageTypeJPEG
+			}
+		}
+	}
+	return best, best.MIMEType()
+}
+
+// parseAccept parses the media ranges of an Accept header. An empty header accepts anything.
+func parseAccept(accept string) []acceptEntry {
+	if strings.TrimSpace(accept) == "" {
+		return []acceptEntry{{mimeType: "*/*", q: 1}}
+	}
+
+	var entries []acceptEntry
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		entry := acceptEntry{mimeType: strings.ToLower(strings.TrimSpace(params[0])), q: 1}
+		if entry.mimeType == "" {
+			continue
+		}
+
+		for _, param := range params[1:] {
+			key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok || strings.TrimSpace(key) != "q" {
+				continue
+			}
+			if q, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				entry.q = q
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// acceptQuality returns the quality value of the most specific media range matching mimeType,
+// or 0 if none matches, and whether that range names mimeType explicitly rather than with a
+// wildcard
+func acceptQuality(entries []acceptEntry, mimeType string) (float64, bool) {
+	if mimeType == "" {
+		return 0, false
+	}
+
+	mediaType, _, _ := strings.Cut(mimeType, "/")
+	q, specificity := 0.0, -1
+	for _, e := range entries {
+		var s int
+		switch e.mimeType {
+		case mimeType:
+			s = 2
+		case mediaType + "/*":
+			s = 1
+		case "*/*":
+			s = 0
+		default:
+			continue
+		}
+		if s > specificity {
+			q, specificity = e.q, s
+		}
+	}
+	return q, specificity == 2
+}
@@ -0,0 +1,161 @@
+package vips
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageType_MIMEType(t *testing.T) {
+	assert.Equal(t, "image/jpeg", ImageTypeJPEG.MIMEType())
+	assert.Equal(t, "image/avif", ImageTypeAVIF.MIMEType())
+	assert.Equal(t, "image/svg+xml", ImageTypeSVG.MIMEType())
+	assert.Equal(t, "", ImageTypeUnknown.MIMEType())
+	assert.Equal(t, "", ImageTypeMagick.MIMEType())
+}
+
+func TestParseImageType(t *testing.T) {
+	for input, expected := range map[string]ImageType{
+		"image/webp":        ImageTypeWEBP,
+		"IMAGE/JPEG":        ImageTypeJPEG,
+		"image/jpg":         ImageTypeJPEG,
+		"image/avif; q=0.9": ImageTypeAVIF,
+		"image/heic":        ImageTypeHEIF,
+		"application/pdf":   ImageTypePDF,
+		".jpg":              ImageTypeJPEG,
+		"jpeg":              ImageTypeJPEG,
+		"photo.PNG":         ImageTypePNG,
+		"/tmp/scan.tif":     ImageTypeTIFF,
+		"svg":               ImageTypeSVG,
+		"psd":               ImageTypePSD,
+		"image/x-unknown":   ImageTypeUnknown,
+		"txt":               ImageTypeUnknown,
+		"magick":            ImageTypeUnknown,
+		"":                  ImageTypeUnknown,
+	} {
+		assert.Equal(t, expected, ParseImageType(input), input)
+	}
+
+	for imageType := range imageTypeMIMETypeMap {
+		assert.Equal(t, imageType, ParseImageType(imageType.MIMEType()))
+	}
+}
+
+func TestParseAccept(t *testing.T) {
+	entries := parseAccept("image/avif,image/webp;q=0.9, */*;q=0.1,")
+	assert.Equal(t, []acceptEntry{
+		{mimeType: "image/avif", q: 1},
+		{mimeType: "image/webp", q: 0.9},
+		{mimeType: "*/*", q: 0.1},
+	}, entries)
+
+	q, explicit := acceptQuality(entries, "image/png")
+	assert.Equal(t, 0.1, q)
+	assert.False(t, explicit)
+	q, explicit = acceptQuality(entries, "image/webp")
+	assert.Equal(t, 0.9, q)
+	assert.True(t, explicit)
+
+	entries = parseAccept("image/*;q=0.5, image/webp;q=0")
+	q, _ = acceptQuality(entries, "image/webp")
+	assert.Equal(t, 0.0, q)
+	q, explicit = acceptQuality(entries, "image/jpeg")
+	assert.Equal(t, 0.5, q)
+	assert.False(t, explicit)
+	q, _ = acceptQuality(parseAccept("text/html"), "image/jpeg")
+	assert.Equal(t, 0.0, q)
+	q, _ = acceptQuality(parseAccept(""), "image/jpeg")
+	assert.Equal(t, 1.0, q)
+}
+
+func TestNegotiateFormat(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	policy := NegotiationPolicy{Preferred: []ImageType{ImageTypeWEBP, ImageTypeJPEG, ImageTypePNG, ImageTypeGIF}}
+
+	imageType, mimeType := NegotiateFormat("image/webp,*/*;q=0.8", nil, policy)
+	assert.Equal(t, ImageTypeWEBP, imageType)
+	assert.Equal(t, "image/webp", mimeType)
+
+	imageType, _ = NegotiateFormat("image/jpeg, image/png;q=0.5", nil, policy)
+	assert.Equal(t, ImageTypeJPEG, imageType)
+
+	imageType, _ = NegotiateFormat("", nil, policy)
+	assert.Equal(t, ImageTypeJPEG, imageType)
+
+	imageType, _ = NegotiateFormat("image/png;q=0.5, */*", nil, policy)
+	assert.Equal(t, ImageTypePNG, imageType)
+
+	imageType, _ = NegotiateFormat("text/html", nil, policy)
+	assert.Equal(t, ImageTypeJPEG, imageType)
+
+	imageType, _ = NegotiateFormat("text/html", nil, NegotiationPolicy{Fallback: ImageTypePNG})
+	assert.Equal(t, ImageTypePNG, imageType)
+}
+
+func TestNegotiateFormat_DefaultPolicy(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	// Wildcards never pick the newer formats, whatever the build can save
+	for _, accept := range []string{"", "*/*", "image/*", "text/html,*/*;q=0.8"} {
+		imageType, _ := NegotiateFormat(accept, nil, NegotiationPolicy{})
+		assert.Equal(t, ImageTypeJPEG, imageType, accept)
+	}
+
+	if IsSaveTypeSupported(ImageTypeWEBP) {
+		imageType, _ := NegotiateFormat("image/webp,*/*", nil, NegotiationPolicy{})
+		assert.Equal(t, ImageTypeWEBP, imageType)
+	}
+
+	if IsSaveTypeSupported(ImageTypeAVIF) {
+		imageType, _ := NegotiateFormat("image/avif,image/webp,*/*", nil, NegotiationPolicy{})
+		assert.Equal(t, ImageTypeAVIF, imageType)
+	}
+}
+
+func TestNegotiateFormat_Alpha(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	img, err := NewImageFromFile(resources + "png-24bit+alpha.png")
+	require.NoError(t, err)
+	defer img.Close()
+
+	policy := NegotiationPolicy{Preferred: []ImageType{ImageTypeJPEG, ImageTypePNG}}
+
+	imageType, mimeType := NegotiateFormat("image/jpeg,image/png", img, policy)
+	assert.Equal(t, ImageTypePNG, imageType)
+	assert.Equal(t, "image/png", mimeType)
+
+	imageType, _ = NegotiateFormat("image/jpeg", img, policy)
+	assert.Equal(t, ImageTypePNG, imageType)
+
+	policy.IgnoreAlpha = true
+	imageType, _ = NegotiateFormat("image/jpeg,image/png", img, policy)
+	assert.Equal(t, ImageTypeJPEG, imageType)
+}
+
+func TestNegotiateFormat_Animated(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	img, err := NewImageFromFile(resources + "gif-animated.gif")
+	require.NoError(t, err)
+	defer img.Close()
+
+	imageType, _ := NegotiateFormat("image/jpeg,image/png", img, NegotiationPolicy{})
+	assert.Equal(t, ImageTypeGIF, imageType)
+
+	imageType, _ = NegotiateFormat("image/webp,image/gif", img, NegotiationPolicy{
+		Preferred: []ImageType{ImageTypeWEBP, ImageTypeGIF},
+	})
+	assert.Equal(t, ImageTypeWEBP, imageType)
+}
+
+func TestIsSaveTypeSupported(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	assert.True(t, IsSaveTypeSupported(ImageTypeJPEG))
+	assert.True(t, IsSaveTypeSupported(ImageTypePNG))
+	assert.False(t, IsSaveTypeSupported(ImageTypeSVG))
+	assert.False(t, IsSaveTypeSupported(ImageTypeUnknown))
+}
@@ -35,6 +35,10 @@ int minOp(VipsImage *in, double *out, int *x, int *y, int size) {
   return vips_min(in, out, "x", x, "y", y, "size", size, NULL);
 }
 
+int maxOp(VipsImage *in, double *out, int *x, int *y, int size) {
+  return vips_max(in, out, "x", x, "y", y, "size", size, NULL);
+}
+
 // Color
 
 int is_colorspace_supported(VipsImage *in) {
@@ -314,6 +318,14 @@ unsigned long has_iptc(VipsImage *in) {
   return vips_image_get_typeof(in, VIPS_META_IPTC_NAME);
 }
 
+unsigned long has_exif(VipsImage *in) {
+  return vips_image_get_typeof(in, VIPS_META_EXIF_NAME);
+}
+
+unsigned long has_xmp(VipsImage *in) {
+  return vips_image_get_typeof(in, VIPS_META_XMP_NAME);
+}
+
 char **image_get_fields(VipsImage *in) { return vips_image_get_fields(in); }
 
 void image_set_string(VipsImage *in, const char *name, const char *str) {
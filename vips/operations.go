@@ -55,6 +55,18 @@ func vipsMin(in *C.VipsImage) (float64, int, int, error) {
 	return float64(out), int(x), int(y), nil
 }
 
+func vipsMax(in *C.VipsImage) (float64, int, int, error) {
+	incOpCounter("max")
+	var out C.double
+	var x, y C.int
+
+	if err := C.maxOp(in, &out, &x, &y, C.int(1)); err != 0 {
+		return 0, 0, 0, handleVipsError()
+	}
+
+	return float64(out), int(x), int(y), nil
+}
+
 // Color
 
 // Color represents an RGB
@@ -405,6 +417,75 @@ func vipsExtractAreaMultiPage(in *C.VipsImage, left, top, width, height int) (*C
 	return out, nil
 }
 
+// vipsMapPages applies fn to every page of a multi-page image and joins the results back
+// together. fn must give every page the same size.
+func vipsMapPages(in *C.VipsImage, fn func(page *C.VipsImage) (*C.VipsImage, error)) (*C.VipsImage, error) {
+	incOpCounter("mapPages")
+
+	pageHeight := vipsGetPageHeight(in)
+	nPages := int(in.Ysize) / pageHeight
+
+	pages := make([]*C.VipsImage, 0, nPages)
+	defer func() {
+		for _, p := range pages {
+			clearImage(p)
+		}
+	}()
+
+	for i := 0; i < nPages; i++ {
+		page, err := vipsGenExtractArea(in, 0, pageHeight*i, int(in.Xsize), pageHeight)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := fn(page)
+		clearImage(page)
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, out)
+	}
+
+	across := 1
+	joined, err := vipsGenArrayjoin(pages, &ArrayjoinOptions{Across: &across})
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := vipsGenCopy(joined, nil)
+	clearImage(joined)
+	if err != nil {
+		return nil, err
+	}
+
+	vipsSetPageHeight(out, int(pages[0].Ysize))
+	return out, nil
+}
+
+// vipsBackground converts color to a background for in, matching its bands and range
+func vipsBackground(in *C.VipsImage, color *ColorRGBA) []float64 {
+	if color == nil {
+		return nil
+	}
+
+	scale := 1.0
+	if interpretation := Interpretation(in.Type); interpretation == InterpretationRGB16 || interpretation == InterpretationGrey16 {
+		scale = 65535.0 / 255.0
+	}
+	r, g, b, a := float64(color.R)*scale, float64(color.G)*scale, float64(color.B)*scale, float64(color.A)*scale
+
+	switch in.Bands {
+	case 1:
+		return []float64{r}
+	case 2:
+		return []float64{r, a}
+	case 3:
+		return []float64{r, g, b}
+	default:
+		return []float64{r, g, b, a}
+	}
+}
+
 // http://libvips.github.io/libvips/API/current/libvips-resample.html#vips-similarity
 func vipsSimilarity(in *C.VipsImage, scale float64, angle float64, color *ColorRGBA,
 	idx float64, idy float64, odx float64, ody float64) (*C.VipsImage, error) {
@@ -597,6 +678,14 @@ func vipsHasIPTC(in *C.VipsImage) bool {
 	return int(C.has_iptc(in)) != 0
 }
 
+func vipsHasExif(in *C.VipsImage) bool {
+	return int(C.has_exif(in)) != 0
+}
+
+func vipsHasXMP(in *C.VipsImage) bool {
+	return int(C.has_xmp(in)) != 0
+}
+
 func vipsImageGetFields(in *C.VipsImage) (fields []string) {
 	const maxFields = 256
 
@@ -737,7 +826,15 @@ func vipsImageGetBackground(in *C.VipsImage) ([]float64, error) {
 // vipsDetermineImageTypeFromMetaLoader determine the image type from vips-loader metadata
 func vipsDetermineImageTypeFromMetaLoader(in *C.VipsImage) ImageType {
 	vipsLoader, ok := vipsImageGetMetaLoader(in)
-	if vipsLoader == "" || !ok {
+	if !ok {
+		return ImageTypeUnknown
+	}
+	return imageTypeFromLoader(vipsLoader)
+}
+
+// imageTypeFromLoader maps a libvips loader nickname (e.g. jpegload_buffer) to its image type
+func imageTypeFromLoader(vipsLoader string) ImageType {
+	if vipsLoader == "" {
 		return ImageTypeUnknown
 	}
 	if strings.HasPrefix(vipsLoader, "jpeg") {
@@ -1010,6 +1107,25 @@ func vipsThumbnail(in *C.VipsImage, width, height int, crop Interesting, size Si
 
 // https://www.libvips.org/API/current/libvips-resample.html#vips-thumbnail
 func vipsThumbnailFromFile(filename string, width, height int, crop Interesting, size Size, params *ImportParams) (*C.VipsImage, ImageType, error) {
+	if allowedLoaders != nil {
+		imageType, err := vipsDetermineFileType(filename)
+		if err != nil {
+			return nil, ImageTypeUnknown, err
+		}
+		if !isLoaderAllowed(imageType) {
+			return nil, imageType, ErrUnsupportedImageFormat
+		}
+	}
+
+	// libvips has no limits of its own, so read the header first to check them
+	if hasImageLimits(params) {
+		header, _, _, err := vipsLoadFromFile(filename, params)
+		if err != nil {
+			return nil, ImageTypeUnknown, err
+		}
+		clearImage(header)
+	}
+
 	var out *C.VipsImage
 
 	filenameOption := filename
@@ -1038,6 +1154,19 @@ func vipsThumbnailFromBuffer(buf []byte, width, height int, crop Interesting, si
 	// Reference src here so it's not garbage collected during image initialization.
 	defer runtime.KeepAlive(src)
 
+	if imageType := DetermineImageType(src); !isLoaderAllowed(imageType) {
+		return nil, imageType, ErrUnsupportedImageFormat
+	}
+
+	// libvips has no limits of its own, so read the header first to check them
+	if hasImageLimits(params) {
+		header, _, _, err := vipsLoadFromBuffer(src, params)
+		if err != nil {
+			return nil, ImageTypeUnknown, err
+		}
+		clearImage(header)
+	}
+
 	var out *C.VipsImage
 
 	var err C.int
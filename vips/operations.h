@@ -14,6 +14,7 @@ int find_trim(VipsImage *in, int *left, int *top, int *width, int *height,
               double threshold, double r, double g, double b);
 int getpoint(VipsImage *in, double **vector, int n, int x, int y);
 int minOp(VipsImage *in, double *out, int *x, int *y, int size);
+int maxOp(VipsImage *in, double *out, int *x, int *y, int size);
 
 // Color
 // https://libvips.github.io/libvips/API/current/libvips-colour.html
@@ -79,6 +80,8 @@ unsigned long get_icc_profile(VipsImage *in, const void **data,
 int remove_icc_profile(VipsImage *in);
 
 unsigned long has_iptc(VipsImage *in);
+unsigned long has_exif(VipsImage *in);
+unsigned long has_xmp(VipsImage *in);
 char **image_get_fields(VipsImage *in);
 
 void image_set_string(VipsImage *in, const char *name, const char *str);
@@ -0,0 +1,46 @@
+#include "source.h"
+
+static gint64 go_source_read(VipsSourceCustom *source, void *buffer,
+                             gint64 length, gpointer user_data) {
+  return goSourceRead((uintptr_t)user_data, buffer, length);
+}
+
+static gint64 go_source_seek(VipsSourceCustom *source, gint64 offset,
+                             int whence, gpointer user_data) {
+  return goSourceSeek((uintptr_t)user_data, offset, whence);
+}
+
+// Called when the signal handler is disconnected, i.e. when the source is
+// finalized, so the Go side can drop its reference to the reader.
+static void go_source_release(gpointer user_data, GClosure *closure) {
+  goSourceRelease((uintptr_t)user_data);
+}
+
+VipsSource *create_go_source(uintptr_t handle) {
+  VipsSourceCustom *source = vips_source_custom_new();
+  if (!source) {
+    return NULL;
+  }
+
+  g_signal_connect_data(source, "read", G_CALLBACK(go_source_read),
+                        (gpointer)handle, go_source_release, 0);
+  g_signal_connect(source, "seek", G_CALLBACK(go_source_seek),
+                   (gpointer)handle);
+
+  return VIPS_SOURCE(source);
+}
+
+gint64 sniff_source(VipsSource *source, unsigned char **data, size_t length) {
+  return vips_source_sniff_at_most(source, data, length);
+}
+
+// Returns the nickname of the loader libvips would pick for the source, e.g.
+// "jpegload_source", or NULL if the format is not recognised.
+const char *find_source_loader(VipsSource *source) {
+  const char *name = vips_foreign_find_load_source(source);
+  if (!name) {
+    return NULL;
+  }
+
+  return vips_nickname_find(g_type_from_name(name));
+}
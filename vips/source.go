@@ -0,0 +1,132 @@
+package vips
+
+// #include "foreign.h"
+// #include "source.h"
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime/cgo"
+	"unsafe"
+)
+
+// sniffLength is the number of header bytes inspected to determine the type of a streamed image
+const sniffLength = 4096
+
+// maxEmptyReads bounds the number of consecutive (0, nil) reads tolerated from a reader
+const maxEmptyReads = 100
+
+// goSourceRead is called by libvips whenever a streamed source needs more bytes.
+// It returns the number of bytes read, 0 on EOF and -1 on error.
+//
+//export goSourceRead
+func goSourceRead(handle C.uintptr_t, buffer unsafe.Pointer, length C.gint64) C.gint64 {
+	r := cgo.Handle(handle).Value().(io.Reader)
+	buf := unsafe.Slice((*byte)(buffer), int(length))
+
+	for i := 0; i < maxEmptyReads; i++ {
+		n, err := r.Read(buf)
+		if n > 0 {
+			return C.gint64(n)
+		}
+		if errors.Is(err, io.EOF) {
+			return 0
+		}
+		if err != nil {
+			govipsLog("govips", LogLevelError, fmt.Sprintf("failed to read from source: %v", err))
+			return -1
+		}
+	}
+
+	govipsLog("govips", LogLevelError, fmt.Sprintf("failed to read from source: %v", io.ErrNoProgress))
+	return -1
+}
+
+// goSourceSeek is called by libvips to reposition a streamed source. Readers which do not
+// implement io.Seeker report -1, in which case libvips treats the source as a pipe.
+//
+//export goSourceSeek
+func goSourceSeek(handle C.uintptr_t, offset C.gint64, whence C.int) C.gint64 {
+	s, ok := cgo.Handle(handle).Value().(io.Seeker)
+	if !ok {
+		return -1
+	}
+
+	n, err := s.Seek(int64(offset), int(whence))
+	if err != nil {
+		return -1
+	}
+	return C.gint64(n)
+}
+
+// goSourceRelease is called once libvips finalizes a streamed source.
+//
+//export goSourceRelease
+func goSourceRelease(handle C.uintptr_t) {
+	cgo.Handle(handle).Delete()
+}
+
+func vipsLoadFromReader(r io.Reader, params *ImportParams) (*C.VipsImage, ImageType, ImageType, error) {
+	incOpCounter("load_source")
+
+	handle := cgo.NewHandle(r)
+	source := C.create_go_source(C.uintptr_t(handle))
+	if source == nil {
+		handle.Delete()
+		return nil, ImageTypeUnknown, ImageTypeUnknown, handleVipsError()
+	}
+	// The loaded image holds its own reference to the source for as long as it needs pixels.
+	defer C.g_object_unref(C.gpointer(source))
+
+	originalType := vipsDetermineSourceType(source)
+	currentType := originalType
+
+	// Map image types which are not supported by libvips itself to ImageMagick
+	if isNeedToChangeLoaderToMagick(originalType) {
+		currentType = ImageTypeMagick
+	}
+
+	if !IsTypeSupported(currentType) {
+		govipsLog("govips", LogLevelInfo, "failed to understand image format from reader")
+		return nil, currentType, originalType, ErrUnsupportedImageFormat
+	}
+
+	if !isLoaderAllowed(originalType) {
+		govipsLog("govips", LogLevelInfo, fmt.Sprintf("loading image type %s is not allowed", ImageTypes[originalType]))
+		return nil, currentType, originalType, ErrUnsupportedImageFormat
+	}
+
+	importParams := createImportParams(currentType, params)
+
+	if err := C.load_from_source(&importParams, source); err != 0 {
+		return nil, currentType, originalType, handleImageError(importParams.outputImage)
+	}
+
+	if err := checkImageLimits(importParams.outputImage, params); err != nil {
+		clearImage(importParams.outputImage)
+		return nil, currentType, originalType, err
+	}
+
+	return importParams.outputImage, currentType, originalType, nil
+}
+
+// vipsDetermineSourceType sniffs the header of a source without consuming it. The Go-side
+// detection used for buffers is tried first so both paths agree, then libvips' own sniffers.
+func vipsDetermineSourceType(source *C.VipsSource) ImageType {
+	var data *C.uchar
+	if n := C.sniff_source(source, &data, sniffLength); n > 0 {
+		header := C.GoBytes(unsafe.Pointer(data), C.int(n))
+		if imageType := DetermineImageType(header); imageType != ImageTypeUnknown {
+			return imageType
+		}
+	}
+
+	loader := C.find_source_loader(source)
+	if loader == nil {
+		C.vips_error_clear()
+		return ImageTypeUnknown
+	}
+	return imageTypeFromLoader(C.GoString(loader))
+}
@@ -0,0 +1,17 @@
+// https://www.libvips.org/API/current/VipsSource.html
+
+// clang-format off
+// include order matters
+#include <stdint.h>
+#include <stdlib.h>
+#include <glib.h>
+#include <vips/vips.h>
+// clang-format on
+
+extern gint64 goSourceRead(uintptr_t handle, void *buffer, gint64 length);
+extern gint64 goSourceSeek(uintptr_t handle, gint64 offset, int whence);
+extern void goSourceRelease(uintptr_t handle);
+
+VipsSource *create_go_source(uintptr_t handle);
+gint64 sniff_source(VipsSource *source, unsigned char **data, size_t length);
+const char *find_source_loader(VipsSource *source);
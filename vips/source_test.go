@@ -0,0 +1,89 @@
+package vips
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pipeReader hides any io.Seeker implementation of the wrapped reader.
+type pipeReader struct {
+	r io.Reader
+}
+
+func (p *pipeReader) Read(b []byte) (int, error) {
+	return p.r.Read(b)
+}
+
+func TestLoadImageFromReader__Seekable(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	f, err := os.Open(resources + "jpg-24bit.jpg")
+	require.NoError(t, err)
+	defer f.Close()
+
+	img, err := LoadImageFromReader(f, nil)
+	require.NoError(t, err)
+	defer img.Close()
+
+	assert.Equal(t, ImageTypeJPEG, img.Format())
+	assert.Equal(t, ImageTypeJPEG, img.OriginalFormat())
+
+	buf, _, err := img.ExportJpeg(nil)
+	require.NoError(t, err)
+	assert.NotEmpty(t, buf)
+}
+
+func TestLoadImageFromReader__Pipe(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	srcBytes, err := os.ReadFile(resources + "png-24bit.png")
+	require.NoError(t, err)
+
+	img, err := LoadImageFromReader(&pipeReader{r: bytes.NewReader(srcBytes)}, nil)
+	require.NoError(t, err)
+	defer img.Close()
+
+	expected, err := NewImageFromBuffer(srcBytes)
+	require.NoError(t, err)
+	defer expected.Close()
+
+	assert.Equal(t, ImageTypePNG, img.Format())
+	assert.Equal(t, expected.Width(), img.Width())
+	assert.Equal(t, expected.Height(), img.Height())
+
+	_, _, err = img.ExportPng(nil)
+	require.NoError(t, err)
+}
+
+func TestLoadImageFromReader__Sequential(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	f, err := os.Open(resources + "jpg-24bit.jpg")
+	require.NoError(t, err)
+	defer f.Close()
+
+	params := NewImportParams()
+	params.Access.Set(AccessSequential)
+
+	img, err := LoadImageFromReader(&pipeReader{r: f}, params)
+	require.NoError(t, err)
+	defer img.Close()
+
+	require.NoError(t, img.Resize(0.5, KernelAuto))
+	_, _, err = img.ExportWebp(nil)
+	require.NoError(t, err)
+}
+
+func TestLoadImageFromReader__Unsupported(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	img, err := LoadImageFromReader(bytes.NewReader([]byte("definitely not an image file")), nil)
+	assert.Nil(t, img)
+	assert.True(t, errors.Is(err, ErrUnsupportedImageFormat))
+}
@@ -0,0 +1,24 @@
+#include "target.h"
+
+static gint64 go_target_write(VipsTargetCustom *target, const void *data,
+                              gint64 length, gpointer user_data) {
+  return goTargetWrite((uintptr_t)user_data, (void *)data, length);
+}
+
+// Called when the signal handler is disconnected, i.e. when the target is
+// finalized, so the Go side can drop its reference to the writer.
+static void go_target_release(gpointer user_data, GClosure *closure) {
+  goTargetRelease((uintptr_t)user_data);
+}
+
+VipsTarget *create_go_target(uintptr_t handle) {
+  VipsTargetCustom *target = vips_target_custom_new();
+  if (!target) {
+    return NULL;
+  }
+
+  g_signal_connect_data(target, "write", G_CALLBACK(go_target_write),
+                        (gpointer)handle, go_target_release, 0);
+
+  return VIPS_TARGET(target);
+}
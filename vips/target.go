@@ -0,0 +1,94 @@
+package vips
+
+// #include "foreign.h"
+// #include "target.h"
+import "C"
+
+import (
+	"fmt"
+	"io"
+	"runtime/cgo"
+	"unsafe"
+)
+
+// targetWriter remembers the first error returned by the wrapped writer so it can be
+// reported to the caller instead of the generic libvips write failure.
+type targetWriter struct {
+	w   io.Writer
+	err error
+}
+
+// goTargetWrite is called by libvips whenever an encoder flushes bytes to a streamed target.
+// It returns the number of bytes written or -1 on error.
+//
+//export goTargetWrite
+func goTargetWrite(handle C.uintptr_t, data unsafe.Pointer, length C.gint64) C.gint64 {
+	tw := cgo.Handle(handle).Value().(*targetWriter)
+	if tw.err != nil {
+		return -1
+	}
+
+	buf := unsafe.Slice((*byte)(data), int(length))
+	n, err := tw.w.Write(buf)
+	if err != nil {
+		tw.err = err
+		govipsLog("govips", LogLevelError, fmt.Sprintf("failed to write to target: %v", err))
+		return -1
+	}
+	return C.gint64(n)
+}
+
+// goTargetRelease is called once libvips finalizes a streamed target.
+//
+//export goTargetRelease
+func goTargetRelease(handle C.uintptr_t) {
+	cgo.Handle(handle).Delete()
+}
+
+func vipsSaveToWriter(params C.struct_SaveParams, w io.Writer) error {
+	incOpCounter("save_target")
+	defer freeSaveParams(params)
+
+	tw := &targetWriter{w: w}
+	handle := cgo.NewHandle(tw)
+	target := C.create_go_target(C.uintptr_t(handle))
+	if target == nil {
+		handle.Delete()
+		return handleVipsError()
+	}
+	defer C.g_object_unref(C.gpointer(target))
+
+	params.outputTarget = target
+
+	if err := C.save_to_target(&params); err != 0 {
+		vipsErr := handleVipsError()
+		if tw.err != nil {
+			return tw.err
+		}
+		return vipsErr
+	}
+
+	return tw.err
+}
+
+func vipsSaveToFile(params C.struct_SaveParams, path string) error {
+	incOpCounter("save_file")
+	defer freeSaveParams(params)
+
+	cPath := C.CString(path)
+	defer freeCString(cPath)
+
+	target := C.vips_target_new_to_file(cPath)
+	if target == nil {
+		return handleVipsError()
+	}
+	defer C.g_object_unref(C.gpointer(target))
+
+	params.outputTarget = target
+
+	if err := C.save_to_target(&params); err != 0 {
+		return handleVipsError()
+	}
+
+	return nil
+}
@@ -0,0 +1,14 @@
+// https://www.libvips.org/API/current/VipsTarget.html
+
+// clang-format off
+// include order matters
+#include <stdint.h>
+#include <stdlib.h>
+#include <glib.h>
+#include <vips/vips.h>
+// clang-format on
+
+extern gint64 goTargetWrite(uintptr_t handle, void *data, gint64 length);
+extern void goTargetRelease(uintptr_t handle);
+
+VipsTarget *create_go_target(uintptr_t handle);
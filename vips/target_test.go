@@ -0,0 +1,91 @@
+package vips
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingWriter accepts a limited number of bytes before returning an error.
+type failingWriter struct {
+	remaining int
+	err       error
+}
+
+func (f *failingWriter) Write(b []byte) (int, error) {
+	if len(b) > f.remaining {
+		return 0, f.err
+	}
+	f.remaining -= len(b)
+	return len(b), nil
+}
+
+func TestExportJpegTo(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	img, err := NewImageFromFile(resources + "jpg-24bit.jpg")
+	require.NoError(t, err)
+	defer img.Close()
+
+	var out bytes.Buffer
+	metadata, err := img.ExportJpegTo(&out, nil)
+	require.NoError(t, err)
+	assert.Equal(t, ImageTypeJPEG, metadata.Format)
+	assert.Equal(t, ImageTypeJPEG, DetermineImageType(out.Bytes()))
+
+	expected, _, err := img.ExportJpeg(nil)
+	require.NoError(t, err)
+	assert.Equal(t, expected, out.Bytes())
+}
+
+func TestExportPngTo(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	img, err := NewImageFromFile(resources + "png-24bit.png")
+	require.NoError(t, err)
+	defer img.Close()
+
+	var out bytes.Buffer
+	metadata, err := img.ExportPngTo(&out, nil)
+	require.NoError(t, err)
+	assert.Equal(t, ImageTypePNG, metadata.Format)
+
+	roundTrip, err := NewImageFromBuffer(out.Bytes())
+	require.NoError(t, err)
+	defer roundTrip.Close()
+	assert.Equal(t, img.Width(), roundTrip.Width())
+	assert.Equal(t, img.Height(), roundTrip.Height())
+}
+
+func TestExportWebpTo__FromReader(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	img, err := NewImageFromFile(resources + "jpg-24bit.jpg")
+	require.NoError(t, err)
+	defer img.Close()
+
+	var out bytes.Buffer
+	_, err = img.ExportWebpTo(&out, nil)
+	require.NoError(t, err)
+
+	roundTrip, err := LoadImageFromReader(&pipeReader{r: &out}, nil)
+	require.NoError(t, err)
+	defer roundTrip.Close()
+	assert.Equal(t, ImageTypeWEBP, roundTrip.Format())
+}
+
+func TestExportJpegTo__WriterError(t *testing.T) {
+	require.NoError(t, Startup(nil))
+
+	img, err := NewImageFromFile(resources + "jpg-24bit.jpg")
+	require.NoError(t, err)
+	defer img.Close()
+
+	writeErr := errors.New("disk full")
+	metadata, err := img.ExportJpegTo(&failingWriter{remaining: 16, err: writeErr}, nil)
+	assert.Nil(t, metadata)
+	assert.True(t, errors.Is(err, writeErr))
+}